@@ -2,6 +2,8 @@ package ast
 
 import (
 	"bytes"
+	"strconv"
+	"strings"
 
 	"github.com/dawkaka/go-interpreter/token"
 )
@@ -9,6 +11,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	// Span is the range of source the node was parsed from.
+	Span() token.Span
 }
 
 type Statement interface {
@@ -21,6 +25,11 @@ type Expression interface {
 	expressionNode()
 }
 
+// spanOf returns the span from the start of first to the end of last.
+func spanOf(first, last Node) token.Span {
+	return token.Span{Start: first.Span().Start, End: last.Span().End}
+}
+
 type Program struct {
 	Statements []Statement
 }
@@ -32,6 +41,13 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Span() token.Span {
+	if len(p.Statements) == 0 {
+		return token.Span{}
+	}
+	return spanOf(p.Statements[0], p.Statements[len(p.Statements)-1])
+}
+
 func (p *Program) String() string {
 	out := bytes.Buffer{}
 	for _, st := range p.Statements {
@@ -48,6 +64,15 @@ type LetStatement struct {
 
 func (l *LetStatement) statementNode()       {}
 func (l *LetStatement) TokenLiteral() string { return l.Token.Literal }
+func (l *LetStatement) Span() token.Span {
+	if l.Value != nil {
+		return token.Span{Start: l.Token.Start, End: l.Value.Span().End}
+	}
+	if l.Name != nil {
+		return token.Span{Start: l.Token.Start, End: l.Name.Token.End}
+	}
+	return l.Token.Span()
+}
 func (l *LetStatement) String() string {
 	out := bytes.Buffer{}
 	out.WriteString(l.TokenLiteral() + " ")
@@ -66,6 +91,12 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Span() token.Span {
+	if rs.ReturnValue != nil {
+		return token.Span{Start: rs.Token.Start, End: rs.ReturnValue.Span().End}
+	}
+	return rs.Token.Span()
+}
 func (rs *ReturnStatement) String() string {
 	out := bytes.Buffer{}
 	out.WriteString(rs.TokenLiteral() + " ")
@@ -83,6 +114,7 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Span() token.Span     { return i.Token.Span() }
 func (i *Identifier) String() string {
 	return i.Value
 }
@@ -94,8 +126,29 @@ type IntegerLiteral struct {
 
 func (i *IntegerLiteral) expressionNode()      {}
 func (i *IntegerLiteral) TokenLiteral() string { return i.Token.Literal }
+func (i *IntegerLiteral) Span() token.Span     { return i.Token.Span() }
 func (i *IntegerLiteral) String() string       { return i.Token.Literal }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (f *FloatLiteral) expressionNode()      {}
+func (f *FloatLiteral) TokenLiteral() string { return f.Token.Literal }
+func (f *FloatLiteral) Span() token.Span     { return f.Token.Span() }
+func (f *FloatLiteral) String() string       { return f.Token.Literal }
+
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Span() token.Span     { return sl.Token.Span() }
+func (sl *StringLiteral) String() string       { return strconv.Quote(sl.Value) }
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -104,6 +157,9 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Span() token.Span {
+	return token.Span{Start: pe.Token.Start, End: pe.Right.Span().End}
+}
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -122,6 +178,7 @@ type InfixExpression struct {
 
 func (in *InfixExpression) expressionNode()      {}
 func (in *InfixExpression) TokenLiteral() string { return in.Token.Literal }
+func (in *InfixExpression) Span() token.Span     { return spanOf(in.Left, in.Right) }
 func (in *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -139,6 +196,12 @@ type ExpressionStatement struct {
 
 func (e *ExpressionStatement) statementNode()       {}
 func (e *ExpressionStatement) TokenLiteral() string { return e.Tokken.Literal }
+func (e *ExpressionStatement) Span() token.Span {
+	if e.Expression != nil {
+		return e.Expression.Span()
+	}
+	return e.Tokken.Span()
+}
 func (e *ExpressionStatement) String() string {
 	if e.Expression != nil {
 		return e.Expression.String()
@@ -153,4 +216,186 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Span() token.Span     { return b.Token.Span() }
 func (b *Boolean) String() string       { return b.Token.Literal }
+
+type BlockStatement struct {
+	Token      token.Token
+	Statements []Statement
+	Rbrace     token.Token
+}
+
+func (bs *BlockStatement) statementNode()       {}
+func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Span() token.Span {
+	if bs.Rbrace.End.IsValid() {
+		return token.Span{Start: bs.Token.Start, End: bs.Rbrace.End}
+	}
+	if len(bs.Statements) > 0 {
+		return token.Span{Start: bs.Token.Start, End: bs.Statements[len(bs.Statements)-1].Span().End}
+	}
+	return bs.Token.Span()
+}
+func (bs *BlockStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("{ ")
+	for _, s := range bs.Statements {
+		out.WriteString(s.String())
+	}
+	out.WriteString(" }")
+	return out.String()
+}
+
+// IfExpression holds an if/else expression. An `else if` chain is stored as an
+// Alternative block whose only statement is the nested IfExpression.
+type IfExpression struct {
+	Token       token.Token
+	Condition   Expression
+	Consequence *BlockStatement
+	Alternative *BlockStatement
+}
+
+func (ie *IfExpression) expressionNode()      {}
+func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Span() token.Span {
+	if ie.Alternative != nil {
+		return token.Span{Start: ie.Token.Start, End: ie.Alternative.Span().End}
+	}
+	return token.Span{Start: ie.Token.Start, End: ie.Consequence.Span().End}
+}
+func (ie *IfExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("if ")
+	out.WriteString(ie.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ie.Consequence.String())
+	if ie.Alternative != nil {
+		out.WriteString(" else ")
+		out.WriteString(ie.Alternative.String())
+	}
+	return out.String()
+}
+
+type FunctionLiteral struct {
+	Token      token.Token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (fl *FunctionLiteral) expressionNode()      {}
+func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Span() token.Span {
+	return token.Span{Start: fl.Token.Start, End: fl.Body.Span().End}
+}
+func (fl *FunctionLiteral) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range fl.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(fl.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(fl.Body.String())
+	return out.String()
+}
+
+type CallExpression struct {
+	Token     token.Token
+	Function  Expression
+	Arguments []Expression
+	Rparen    token.Token
+}
+
+func (ce *CallExpression) expressionNode()      {}
+func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Span() token.Span {
+	return token.Span{Start: ce.Function.Span().Start, End: ce.Rparen.End}
+}
+func (ce *CallExpression) String() string {
+	var out bytes.Buffer
+	args := []string{}
+	for _, a := range ce.Arguments {
+		args = append(args, a.String())
+	}
+	out.WriteString(ce.Function.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(args, ", "))
+	out.WriteString(")")
+	return out.String()
+}
+
+type ArrayLiteral struct {
+	Token    token.Token
+	Elements []Expression
+	Rbracket token.Token
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Span() token.Span {
+	return token.Span{Start: al.Token.Start, End: al.Rbracket.End}
+}
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+type IndexExpression struct {
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Span() token.Span {
+	return token.Span{Start: ie.Left.Span().Start, End: ie.Rbracket.End}
+}
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+	return out.String()
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+// HashLiteral keeps its pairs in source order.
+type HashLiteral struct {
+	Token  token.Token
+	Pairs  []HashPair
+	Rbrace token.Token
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Span() token.Span {
+	return token.Span{Start: hl.Token.Start, End: hl.Rbrace.End}
+}
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
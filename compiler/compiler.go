@@ -0,0 +1,342 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/dawkaka/go-interpreter/ast"
+	"github.com/dawkaka/go-interpreter/code"
+	"github.com/dawkaka/go-interpreter/object"
+)
+
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+type Compiler struct {
+	instructions code.Instructions
+	constants    []object.Object
+	symbolTable  *SymbolTable
+
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+}
+
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+}
+
+func New() *Compiler {
+	return &Compiler{
+		instructions: code.Instructions{},
+		constants:    []object.Object{},
+		symbolTable:  NewSymbolTable(),
+	}
+}
+
+// NewWithState returns a compiler that keeps defining globals and constants
+// on top of an earlier compilation, as the REPL does between lines.
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+	return compiler
+}
+
+func (c *Compiler) Compile(node ast.Node) error {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+	case *ast.LetStatement:
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		symbol := c.symbolTable.Define(node.Name.Value)
+		c.emit(code.OpSetGlobal, symbol.Index)
+
+	case *ast.IntegerLiteral:
+		integer := &object.Integer{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			return fmt.Errorf("undefined variable %s", node.Value)
+		}
+		c.emit(code.OpGetGlobal, symbol.Index)
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		case "~":
+			c.emit(code.OpBitNot)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+	case *ast.InfixExpression:
+		return c.compileInfixExpression(node)
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.Compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			if err := c.Compile(pair.Key); err != nil {
+				return err
+			}
+			if err := c.Compile(pair.Value); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpHash, len(node.Pairs)*2)
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+
+	default:
+		return fmt.Errorf("compiler: unsupported node %T", node)
+	}
+	return nil
+}
+
+func (c *Compiler) compileInfixExpression(node *ast.InfixExpression) error {
+	switch node.Operator {
+	case "&&", "||":
+		return c.compileLogicalExpression(node)
+	// The VM only has greater-than opcodes, so a < b is compiled as b > a.
+	case "<", "<=":
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if node.Operator == "<" {
+			c.emit(code.OpGreaterThan)
+		} else {
+			c.emit(code.OpGreaterThanOrEqual)
+		}
+		return nil
+	}
+
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+	switch node.Operator {
+	case "+":
+		c.emit(code.OpAdd)
+	case "-":
+		c.emit(code.OpSub)
+	case "*":
+		c.emit(code.OpMul)
+	case "/":
+		c.emit(code.OpDiv)
+	case "%":
+		c.emit(code.OpMod)
+	case "**":
+		c.emit(code.OpPow)
+	case "&":
+		c.emit(code.OpBitAnd)
+	case "|":
+		c.emit(code.OpBitOr)
+	case "^":
+		c.emit(code.OpBitXor)
+	case "<<":
+		c.emit(code.OpShiftLeft)
+	case ">>":
+		c.emit(code.OpShiftRight)
+	case ">":
+		c.emit(code.OpGreaterThan)
+	case ">=":
+		c.emit(code.OpGreaterThanOrEqual)
+	case "==":
+		c.emit(code.OpEqual)
+	case "!=":
+		c.emit(code.OpNotEqual)
+	default:
+		return fmt.Errorf("unknown operator %s", node.Operator)
+	}
+	return nil
+}
+
+// compileLogicalExpression compiles && and || with jumps, so that the right
+// operand only runs when the left one does not decide the result:
+//
+//	a && b                      a || b
+//	  <a>                         <a>
+//	  OpJumpNotTruthy false       OpJumpNotTruthy right
+//	  <b>                         OpTrue
+//	  OpJumpNotTruthy false       OpJump end
+//	  OpTrue                    right:
+//	  OpJump end                  <b>
+//	false:                        OpJumpNotTruthy false
+//	  OpFalse                     OpTrue
+//	end:                          OpJump end
+//	                            false:
+//	                              OpFalse
+//	                            end:
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	var toFalse, toEnd []int
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+	leftJump := c.emit(code.OpJumpNotTruthy, 9999)
+	if node.Operator == "&&" {
+		toFalse = append(toFalse, leftJump)
+	} else {
+		c.emit(code.OpTrue)
+		toEnd = append(toEnd, c.emit(code.OpJump, 9999))
+		c.changeOperand(leftJump, len(c.instructions))
+	}
+
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+	toFalse = append(toFalse, c.emit(code.OpJumpNotTruthy, 9999))
+	c.emit(code.OpTrue)
+	toEnd = append(toEnd, c.emit(code.OpJump, 9999))
+
+	for _, pos := range toFalse {
+		c.changeOperand(pos, len(c.instructions))
+	}
+	c.emit(code.OpFalse)
+	for _, pos := range toEnd {
+		c.changeOperand(pos, len(c.instructions))
+	}
+	return nil
+}
+
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	// Bogus offsets, patched once the size of each branch is known.
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+	if err := c.compileBranch(node.Consequence); err != nil {
+		return err
+	}
+	jumpPos := c.emit(code.OpJump, 9999)
+	c.changeOperand(jumpNotTruthyPos, len(c.instructions))
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else if err := c.compileBranch(node.Alternative); err != nil {
+		return err
+	}
+	c.changeOperand(jumpPos, len(c.instructions))
+	return nil
+}
+
+// compileBranch compiles the block of an if expression so that it leaves
+// exactly one value on the stack.
+func (c *Compiler) compileBranch(block *ast.BlockStatement) error {
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.instructions,
+		Constants:    c.constants,
+	}
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+	c.setLastInstruction(op, pos)
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.instructions)
+	c.instructions = append(c.instructions, ins...)
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	c.previousInstruction = c.lastInstruction
+	c.lastInstruction = EmittedInstruction{Opcode: op, Position: pos}
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.instructions) == 0 {
+		return false
+	}
+	return c.lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	c.instructions = c.instructions[:c.lastInstruction.Position]
+	c.lastInstruction = c.previousInstruction
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	for i := 0; i < len(newInstruction); i++ {
+		c.instructions[pos+i] = newInstruction[i]
+	}
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.instructions[opPos])
+	newInstruction := code.Make(op, operand)
+	c.replaceInstruction(opPos, newInstruction)
+}
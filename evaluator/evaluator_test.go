@@ -0,0 +1,876 @@
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dawkaka/go-interpreter/lexer"
+	"github.com/dawkaka/go-interpreter/object"
+	"github.com/dawkaka/go-interpreter/parser"
+)
+
+func testEval(input string) object.Object {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+	return Eval(program, env)
+}
+
+func TestEvalIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"5", 5},
+		{"10", 10},
+		{"-5", -5},
+		{"-10", -10},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"-50 + 100 + -50", 0},
+		{"5 * 2 + 10", 20},
+		{"5 + 2 * 10", 25},
+		{"20 + 2 * -10", 0},
+		{"50 / 2 * 2 + 10", 60},
+		{"2 * (5 + 10)", 30},
+		{"3 * 3 * 3 + 10", 37},
+		{"3 * (3 * 3) + 10", 37},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 7 % 3 * 2", 4},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 | 2 ^ 7 & 4", 7},
+		{"1 << 2 + 1", 8},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"7 ** 0", 1},
+		{"2 * 3 ** 2", 18},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3.5", "3.5"},
+		{"-2.25", "-2.25"},
+		{"1.5 + 1.5", "3.0"},
+		{"1 + 0.5", "1.5"},
+		{"0.5 * 4", "2.0"},
+		{"7 / 2.0", "3.5"},
+		{"7.5 % 2", "1.5"},
+		{"2 ** 0.5 ** 2", "1.189207115002721"},
+		{"0.1 + 0.2", "0.30000000000000004"},
+		{"1e21 * 10", "1e+22"},
+		{"1.0 / 0", "+Inf"},
+		{"-1 / 0.0", "-Inf"},
+		{"0.0 / 0", "NaN"},
+		{"1.5 < 2", "true"},
+		{"2 >= 2.0", "true"},
+		{"1 == 1.0", "true"},
+		{"let nan = 0.0 / 0; nan == nan", "false"},
+		{"let nan = 0.0 / 0; nan != nan", "true"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s (%T)", tt.input, tt.expected, evaluated.Inspect(), evaluated)
+		}
+	}
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true", true},
+		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 < 1", false},
+		{"1 > 1", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"1 == 2", false},
+		{"1 != 2", true},
+		{"true == true", true},
+		{"false == false", true},
+		{"true == false", false},
+		{"true != false", true},
+		{"false != true", true},
+		{"(1 < 2) == true", true},
+		{"(1 < 2) == false", false},
+		{"(1 > 2) == true", false},
+		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1 < 2 == 2 >= 1", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestBangOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"!true", false},
+		{"!false", true},
+		{"!5", false},
+		{"!!true", true},
+		{"!!false", false},
+		{"!!5", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 && \"a\"", true},
+		{"if (false) { 1 } || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 < 3 && 3 < 4", true},
+		// The right side is not evaluated when the left side decides.
+		{"false && missing", false},
+		{"true || missing", true},
+		{"let x = 5; x > 0 && x < 10", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+
+	errObj, ok := testEval("true && missing").(*object.Error)
+	if !ok || errObj.Message != "identifier not found: missing" {
+		t.Errorf("expected the right side to be evaluated, got %+v", errObj)
+	}
+}
+
+func TestIfElseExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (true) { 10 }", 10},
+		{"if (false) { 10 }", nil},
+		{"if (1) { 10 }", 10},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 > 2) { 10 }", nil},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 30 } else { 20 }", 30},
+		{"if (true) { }", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"return 10;", 10},
+		{"return 10; 9;", 10},
+		{"return 2 * 5; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{`
+if (10 > 1) {
+  if (10 > 1) {
+    return 10;
+  }
+
+  return 1;
+}
+`, 10},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"5; true + false; 5", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { true + false; }", "unknown operator: BOOLEAN + BOOLEAN"},
+		{`
+if (10 > 1) {
+  if (10 > 1) {
+    return true + false;
+  }
+
+  return 1;
+}
+`, "unknown operator: BOOLEAN + BOOLEAN"},
+		{"foobar", "identifier not found: foobar"},
+		{"10 / 0", "division by zero: 10 / 0"},
+		{"1 << 64", "shift amount out of range: 1 << 64"},
+		{"2 ** -1", "negative exponent: 2 ** -1"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"1.5 + true", "type mismatch: FLOAT + BOOLEAN"},
+		{"{1.5: 1}", "unusable as hash key: FLOAT"},
+		{"1 >> -1", "shift amount out of range: 1 >> -1"},
+		{"~true", "unknown operator: ~BOOLEAN"},
+		{"5(1)", "not a function: INTEGER"},
+		{"fn(x) { x }()", "wrong number of arguments: want=1, got=0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5 * 5; a;", 25},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestFunctionObject(t *testing.T) {
+	input := "fn(x) { x + 2; };"
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(fn.Parameters) != 1 {
+		t.Fatalf("function has wrong parameters. Parameters=%+v", fn.Parameters)
+	}
+	if fn.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", fn.Parameters[0])
+	}
+	expectedBody := "{ (x + 2) }"
+	if fn.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, fn.Body.String())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let identity = fn(x) { return x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+	result, ok := obj.(*object.Boolean)
+	if !ok {
+		t.Errorf("object is not Boolean. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%t, want=%t", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+	if obj != NULL {
+		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
+		return false
+	}
+	return true
+}
+
+func TestStringLiteral(t *testing.T) {
+	evaluated := testEval(`"Hello World!"`)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"Hello" + " " + "World!"`, "Hello World!"},
+		{`let greet = fn(name) { "hi, " + name }; greet("bob")`, "hi, bob"},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{`"a" - "b"`, "unknown operator: STRING - STRING"},
+		{`"a" + 1`, "type mismatch: STRING + INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value. want=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. want=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	evaluated := testEval("[1, 2 * 2, 3 + 3]")
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let myArray = [1, 2, 3]; myArray[2];", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{`len(1)`, "argument 1 to `len` must be STRING, ARRAY or HASH, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments to `len`: got=2, want=1"},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, nil},
+		{`first(1)`, "argument 1 to `first` must be ARRAY, got INTEGER"},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, nil},
+		{`last(1)`, "argument 1 to `last` must be ARRAY, got INTEGER"},
+		{`rest([1, 2, 3])`, []int{2, 3}},
+		{`rest([])`, nil},
+		{`push([], 1)`, []int{1}},
+		{`let a = [1]; push(a, 2); a`, []int{1}},
+		{`push(1, 1)`, "argument 1 to `push` must be ARRAY, got INTEGER"},
+		{`push([])`, "wrong number of arguments to `push`: got=1, want=2"},
+		{`let len = fn(x) { 42 }; len([1])`, 42},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d", len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
+func TestTypeAndPutsBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`type(1)`, "INTEGER"},
+		{`type("a")`, "STRING"},
+		{`type([])`, "ARRAY"},
+		{`type({})`, "HASH"},
+		{`type(fn(x) { x })`, "FUNCTION"},
+		{`type(len)`, "BUILTIN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: object is not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("%s: wrong type. want=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+
+	var out bytes.Buffer
+	env := object.NewEnvironment()
+	env.Set("puts", object.NewPuts(&out))
+	program := parser.New(lexer.New(`puts("hello", 1, [2])`)).ParseProgram()
+	testNullObject(t, Eval(program, env))
+	if got, want := out.String(), "hello\n1\n[2]\n"; got != want {
+		t.Errorf("puts wrote %q, want %q", got, want)
+	}
+}
+
+func TestHostRegisteredBuiltin(t *testing.T) {
+	object.Register("double", func(args ...object.Object) object.Object {
+		if err := object.CheckArgs("double", args, object.INTEGER_OBJ); err != nil {
+			return err
+		}
+		return &object.Integer{Value: 2 * args[0].(*object.Integer).Value}
+	})
+
+	testIntegerObject(t, testEval(`double(21)`), 42)
+	testIntegerObject(t, testEval(`let double = fn(x) { x }; double(21)`), 21)
+
+	errObj, ok := testEval(`double("a")`).(*object.Error)
+	if !ok {
+		t.Fatalf("expected error for double(\"a\")")
+	}
+	if want := "argument 1 to `double` must be INTEGER, got STRING"; errObj.Message != want {
+		t.Errorf("wrong error message. expected=%q, got=%q", want, errObj.Message)
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                             5,
+		FALSE.HashKey():                            6,
+	}
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`len({1: 2, 3: 4})`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestUnhashableKeys(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedStart   string
+	}{
+		{`{"name": "x", fn(x) { x }: 1}`, "unusable as hash key: FUNCTION", "1:15"},
+		{`{[1]: 1}`, "unusable as hash key: ARRAY", "1:2"},
+		{`{"name": "x"}[fn(x) { x }];`, "unusable as hash key: FUNCTION", "1:1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+		if errObj.Span.Start.String() != tt.expectedStart {
+			t.Errorf("wrong error position. expected=%s, got=%s", tt.expectedStart, errObj.Span.Start)
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+let newAdder = fn(x) {
+  fn(y) { x + y };
+};
+let addTwo = newAdder(2);
+addTwo(2);`, 4},
+		// Each counter keeps its own captured count.
+		{`
+let makeCounter = fn(start) {
+  { "count": start, "next": fn() { makeCounter(start + 1) } }
+};
+let counter = makeCounter(0);
+let advanced = counter["next"]()["next"]();
+let other = makeCounter(10)["next"]();
+advanced["count"] * 100 + other["count"] + counter["count"];`, 211},
+		// Inner bindings shadow outer ones without changing them.
+		{`
+let x = 10;
+let f = fn(x) { let g = fn() { x }; g() };
+f(1) + x;`, 11},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestCurrying(t *testing.T) {
+	input := `
+let curry = fn(f) { fn(a) { fn(b) { f(a, b) } } };
+let add = fn(a, b) { a + b };
+let mul = fn(a, b) { a * b };
+let addThree = curry(add)(3);
+let double = curry(mul)(2);
+addThree(4) + double(10) + curry(add)(1)(1);`
+
+	testIntegerObject(t, testEval(input), 29)
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };
+fact(10);`, 3628800},
+		{`
+let wrapper = fn() {
+  let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+  fib(15)
+};
+wrapper();`, 610},
+		{`
+let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+if (isEven(10)) { 1 } else { 0 };`, 1},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestHigherOrderFunctions(t *testing.T) {
+	prelude := `
+let map = fn(arr, f) {
+  let iter = fn(arr, accumulated) {
+    if (len(arr) == 0) {
+      accumulated
+    } else {
+      iter(rest(arr), push(accumulated, f(first(arr))));
+    }
+  };
+  iter(arr, []);
+};
+let reduce = fn(arr, initial, f) {
+  let iter = fn(arr, result) {
+    if (len(arr) == 0) {
+      result
+    } else {
+      iter(rest(arr), f(result, first(arr)));
+    }
+  };
+  iter(arr, initial);
+};
+let sum = fn(arr) { reduce(arr, 0, fn(initial, el) { initial + el }) };
+`
+
+	evaluated := testEval(prelude + "map([1, 2, 3, 4], fn(x) { x * 2 });")
+	array, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	for i, want := range []int64{2, 4, 6, 8} {
+		testIntegerObject(t, array.Elements[i], want)
+	}
+
+	testIntegerObject(t, testEval(prelude+"sum([1, 2, 3, 4, 5]);"), 15)
+	testIntegerObject(t, testEval(prelude+"let factor = 3; sum(map([1, 2], fn(x) { x * factor }));"), 9)
+}
+
+func TestReturnLeavesInnermostFunction(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+let inner = fn() { return 1; 100 };
+let outer = fn() { let a = inner(); a + 10 };
+outer();`, 11},
+		{`
+let outer = fn() {
+  let f = fn(x) { if (x > 0) { return x; } return 0; };
+  f(5) + f(-5) + 1
+};
+outer();`, 6},
+		{`
+let apply = fn(f) { f(); 7 };
+apply(fn() { return 3; });`, 7},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		limits   object.Limits
+		expected object.LimitKind
+	}{
+		{"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(100)", object.Limits{MaxSteps: 50}, object.LimitSteps},
+		{"let f = fn(n) { f(n + 1) }; f(0)", object.Limits{MaxCallDepth: 100}, object.LimitCallDepth},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.SetMeter(object.NewMeter(context.Background(), tt.limits))
+		p := parser.New(lexer.New(tt.input))
+		evaluated := Eval(p.ParseProgram(), env)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: expected error, got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		limitErr, ok := errObj.Err.(*object.LimitExceeded)
+		if !ok {
+			t.Errorf("%s: expected LimitExceeded, got %v", tt.input, errObj.Err)
+			continue
+		}
+		if limitErr.Kind != tt.expected {
+			t.Errorf("%s: wrong limit. want=%s, got=%s", tt.input, tt.expected, limitErr.Kind)
+		}
+	}
+
+	env := object.NewEnvironment()
+	env.SetMeter(object.NewMeter(context.Background(), object.Limits{MaxCallDepth: 10}))
+	p := parser.New(lexer.New("let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(9) + f(9)"))
+	testIntegerObject(t, Eval(p.ParseProgram(), env), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	env = object.NewEnvironment()
+	env.SetMeter(object.NewMeter(ctx, object.Limits{}))
+	p = parser.New(lexer.New("let f = fn(n) { f(n + 1) }; f(0)"))
+	errObj, ok := Eval(p.ParseProgram(), env).(*object.Error)
+	if !ok || !errors.Is(errObj.Err, context.Canceled) {
+		t.Errorf("expected evaluation to stop with context.Canceled, got %+v", errObj)
+	}
+}
+
+func TestMemoryAccounting(t *testing.T) {
+	env := object.NewEnvironment()
+	env.SetMeter(object.NewMeter(context.Background(), object.Limits{MaxMemory: 1 << 20}))
+	p := parser.New(lexer.New(`let grow = fn(s, n) { if (n == 0) { s } else { grow(s + s, n - 1) } }; grow("ab", 30)`))
+	errObj, ok := Eval(p.ParseProgram(), env).(*object.Error)
+	if !ok {
+		t.Fatalf("expected error")
+	}
+	if limitErr, ok := errObj.Err.(*object.LimitExceeded); !ok || limitErr.Kind != object.LimitMemory {
+		t.Fatalf("expected memory LimitExceeded, got %v", errObj.Err)
+	}
+
+	// Garbage made inside a call is released when it returns, but what the
+	// call returns stays counted.
+	usage := func(input string) int {
+		meter := object.NewMeter(context.Background(), object.Limits{})
+		env := object.NewEnvironment()
+		env.SetMeter(meter)
+		Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+		return meter.Memory()
+	}
+	prelude := `let g = fn() { let s = "aaaaaaaaaa" + "bbbbbbbbbb"; len(s + s) };
+let h = fn() { "aaaaaaaaaa" + "bbbbbbbbbb" };`
+	once, many := usage(prelude+"g();"), usage(prelude+"g(); g(); g(); g();")
+	if once != many {
+		t.Errorf("memory of calls not released. once=%d, four times=%d", once, many)
+	}
+	if kept := usage(prelude + "let x = h();"); kept <= once {
+		t.Errorf("returned string not counted. with=%d, without=%d", kept, once)
+	}
+}
@@ -1,17 +1,46 @@
 package lexer
 
 import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/dawkaka/go-interpreter/token"
 )
 
+// ErrorHandler is called with the span and a description of each malformed
+// piece of input, such as an unterminated string. The lexer still returns a
+// token for it, usually ILLEGAL, and carries on after it.
+type ErrorHandler func(span token.Span, msg string)
+
+// UnterminatedString is the message reported for a string literal that runs
+// to the end of the input. Since strings may span lines, a REPL can take it
+// as a sign that more input is on its way.
+const UnterminatedString = "unterminated string literal"
+
 type Lexer struct {
+	// Error, if set, is called for every lexical error.
+	Error ErrorHandler
+
+	filename     string
 	input        string
 	position     int
 	readPosition int
 	ch           byte
+
+	// line and column of the character at position.
+	line   int
+	column int
 }
 
 func (l *Lexer) readChar() {
+	if l.readPosition > len(l.input) {
+		return
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -19,6 +48,16 @@ func (l *Lexer) readChar() {
 	}
 	l.position = l.readPosition
 	l.readPosition += 1
+	l.column++
+}
+
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
 }
 func (l *Lexer) peekChar() byte {
 	if l.readPosition >= len(l.input) {
@@ -41,16 +80,142 @@ func (l *Lexer) skipWhiteSpace() {
 	}
 }
 
-func (l *Lexer) readNumber() string {
-	pos := l.position
-	for isDigit(l.ch) {
+// readNumber reads an INT or a FLOAT. Integers may be written in hex, octal
+// or binary with a 0x, 0o or 0b prefix. A float has digits on both sides of
+// its decimal point, an exponent, or both, as in "3.14", "1e-9" and
+// "2.5E3"; forms such as ".5" and "5." are not numbers. Digits may be
+// grouped with single underscores, as in "1_000_000".
+//
+// A malformed number is read to its end and returned as one ILLEGAL token.
+func (l *Lexer) readNumber() token.Token {
+	start := l.pos()
+	tok := token.Token{Type: token.INT}
+	if _, ok := basePrefix(l.ch, l.peekChar()); ok {
+		l.readChar()
+		l.readChar()
+		// Letters are read too, so that "0xFG" is one bad number rather
+		// than a number followed by a name.
+		l.readWhile(func(ch byte) bool { return isLetter(ch) || isDigit(ch) })
+	} else {
+		l.readWhile(isDigitOrUnderscore)
+		if l.ch == '.' && isDigit(l.peekChar()) {
+			tok.Type = token.FLOAT
+			l.readChar()
+			l.readWhile(isDigitOrUnderscore)
+		}
+		if l.ch == 'e' || l.ch == 'E' {
+			tok.Type = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			l.readWhile(isDigitOrUnderscore)
+		}
+	}
+	tok.Literal = l.input[start.Offset:l.position]
+	if problem := numberProblem(tok.Literal, tok.Type); problem != "" {
+		l.error(token.Span{Start: start, End: l.pos()}, problem)
+		tok.Type = token.ILLEGAL
+	}
+	return tok
+}
+
+func (l *Lexer) readWhile(accept func(byte) bool) {
+	for accept(l.ch) {
 		l.readChar()
 	}
-	return l.input[pos:l.position]
 }
 
+type base struct {
+	name  string
+	digit func(byte) bool
+}
+
+// bases holds the integer prefixes that follow a 0, keyed by their lower
+// case letter.
+var bases = map[byte]base{
+	'x': {"hexadecimal", isHexDigit},
+	'o': {"octal", func(ch byte) bool { return '0' <= ch && ch <= '7' }},
+	'b': {"binary", func(ch byte) bool { return ch == '0' || ch == '1' }},
+}
+
+// basePrefix reports whether c0 and c1 start a hex, octal or binary integer.
+func basePrefix(c0, c1 byte) (base, bool) {
+	if c0 != '0' {
+		return base{}, false
+	}
+	if 'A' <= c1 && c1 <= 'Z' {
+		c1 += 'a' - 'A'
+	}
+	b, ok := bases[c1]
+	return b, ok
+}
+
+// numberProblem describes what is wrong with the number literal lit of type
+// typ, or returns "" if it is well formed.
+func numberProblem(lit string, typ token.TokenType) string {
+	if len(lit) >= 2 {
+		if base, ok := basePrefix(lit[0], lit[1]); ok {
+			return prefixedProblem(base, lit[2:])
+		}
+	}
+
+	mantissa := lit
+	if i := strings.IndexAny(lit, "eE"); i >= 0 {
+		mantissa = lit[:i]
+		exponent := strings.TrimLeft(lit[i+1:], "+-")
+		if exponent == "" {
+			return "exponent has no digits"
+		}
+		if problem := underscoreProblem(exponent); problem != "" {
+			return problem
+		}
+	}
+	for _, part := range strings.Split(mantissa, ".") {
+		if problem := underscoreProblem(part); problem != "" {
+			return problem
+		}
+	}
+	if typ == token.INT && len(lit) > 1 && lit[0] == '0' {
+		if strings.Trim(lit, "0_") == "" {
+			return fmt.Sprintf("integer %s has a leading zero; write zero as 0", lit)
+		}
+		return fmt.Sprintf("integer %s has a leading zero; write octal numbers as 0o%s", lit, strings.TrimLeft(lit, "0_"))
+	}
+	return ""
+}
+
+func prefixedProblem(b base, digits string) string {
+	if strings.Trim(digits, "_") == "" {
+		return b.name + " literal has no digits"
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] != '_' && !b.digit(digits[i]) {
+			return fmt.Sprintf("invalid digit %q in %s literal", digits[i], b.name)
+		}
+	}
+	return underscoreProblem(digits)
+}
+
+func underscoreProblem(digits string) string {
+	if strings.HasPrefix(digits, "_") || strings.HasSuffix(digits, "_") || strings.Contains(digits, "__") {
+		return "'_' must separate successive digits"
+	}
+	return ""
+}
+
+// NextToken returns the next token in the input along with the span it
+// covers. Once the input is exhausted it keeps returning EOF.
 func (l *Lexer) NextToken() token.Token {
 	l.skipWhiteSpace()
+	start := l.pos()
+	tok := l.nextToken()
+	tok.Start = start
+	tok.End = l.pos()
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
 	c := l.ch
 	var tok token.Token
 	switch c {
@@ -72,10 +237,16 @@ func (l *Lexer) NextToken() token.Token {
 		tok = AssignToken(token.LBRACE, c)
 	case '}':
 		tok = AssignToken(token.RBRACE, c)
+	case '[':
+		tok = AssignToken(token.LBRACKET, c)
+	case ']':
+		tok = AssignToken(token.RBRACKET, c)
 	case ',':
 		tok = AssignToken(token.COMMA, c)
 	case ';':
 		tok = AssignToken(token.SEMICOLON, c)
+	case ':':
+		tok = AssignToken(token.COLON, c)
 	case '!':
 		if l.peekChar() == '=' {
 			tok.Type = token.NOT_EQ
@@ -88,45 +259,226 @@ func (l *Lexer) NextToken() token.Token {
 		tok = AssignToken(token.MINUS, c)
 	case '/':
 		tok = AssignToken(token.SLASH, c)
+	case '%':
+		tok = AssignToken(token.PERCENT, c)
 	case '<':
-		tok = AssignToken(token.LT, c)
+		switch l.peekChar() {
+		case '=':
+			tok.Type = token.LT_EQ
+			tok.Literal = "<="
+			l.readChar()
+		case '<':
+			tok.Type = token.SHIFT_LEFT
+			tok.Literal = "<<"
+			l.readChar()
+		default:
+			tok = AssignToken(token.LT, c)
+		}
 	case '>':
-		tok = AssignToken(token.GT, c)
+		switch l.peekChar() {
+		case '=':
+			tok.Type = token.GT_EQ
+			tok.Literal = ">="
+			l.readChar()
+		case '>':
+			tok.Type = token.SHIFT_RIGHT
+			tok.Literal = ">>"
+			l.readChar()
+		default:
+			tok = AssignToken(token.GT, c)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			tok.Type = token.AND
+			tok.Literal = "&&"
+			l.readChar()
+		} else {
+			tok = AssignToken(token.AMPERSAND, c)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok.Type = token.OR
+			tok.Literal = "||"
+			l.readChar()
+		} else {
+			tok = AssignToken(token.PIPE, c)
+		}
+	case '^':
+		tok = AssignToken(token.CARET, c)
+	case '~':
+		tok = AssignToken(token.TILDE, c)
 	case '*':
-		tok = AssignToken(token.ASTERISK, c)
+		if l.peekChar() == '*' {
+			tok.Type = token.POWER
+			tok.Literal = "**"
+			l.readChar()
+		} else {
+			tok = AssignToken(token.ASTERISK, c)
+		}
 
+	case '"':
+		return l.readString()
 	case 0:
-		tok.Type = token.EOF
-		tok.Literal = ""
+		if l.position >= len(l.input) {
+			tok.Type = token.EOF
+			tok.Literal = ""
+			return tok
+		}
+		tok = l.illegalChar()
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
-			return tok
+			return l.readNumber()
 		} else {
-			tok = AssignToken(token.ILLEGAL, l.ch)
+			tok = l.illegalChar()
 		}
 	}
 	l.readChar()
 	return tok
 }
 
+func (l *Lexer) illegalChar() token.Token {
+	start := l.pos()
+	end := start
+	end.Offset++
+	end.Column++
+	l.error(token.Span{Start: start, End: end}, fmt.Sprintf("illegal character %q", l.ch))
+	return AssignToken(token.ILLEGAL, l.ch)
+}
+
+func (l *Lexer) error(span token.Span, msg string) {
+	if l.Error != nil {
+		l.Error(span, msg)
+	}
+}
+
+// readString reads a double-quoted string starting at the opening quote and
+// returns a STRING token whose literal is the unescaped contents. A string
+// that is still open at the end of the input yields an ILLEGAL token.
+func (l *Lexer) readString() token.Token {
+	start := l.pos()
+	var out strings.Builder
+	l.readChar()
+	for l.ch != '"' {
+		if l.ch == 0 && l.position >= len(l.input) {
+			l.error(token.Span{Start: start, End: l.pos()}, UnterminatedString)
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[start.Offset:l.position]}
+		}
+		if l.ch == '\\' {
+			l.readEscape(&out)
+			continue
+		}
+		out.WriteByte(l.ch)
+		l.readChar()
+	}
+	l.readChar()
+	return token.Token{Type: token.STRING, Literal: out.String()}
+}
+
+// readEscape decodes the escape sequence starting at the backslash under
+// the cursor into out. Unknown escapes are reported and kept verbatim.
+func (l *Lexer) readEscape(out *strings.Builder) {
+	start := l.pos()
+	l.readChar()
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case '"':
+		out.WriteByte('"')
+	case '\\':
+		out.WriteByte('\\')
+	case 'u':
+		l.readUnicodeEscape(start, out)
+		return
+	case 0:
+		// Unterminated; readString reports it.
+		out.WriteByte('\\')
+		return
+	default:
+		l.readChar()
+		l.error(token.Span{Start: start, End: l.pos()},
+			fmt.Sprintf("unknown escape sequence %s", l.input[start.Offset:l.position]))
+		out.WriteString(l.input[start.Offset:l.position])
+		return
+	}
+	l.readChar()
+}
+
+// readUnicodeEscape reads the `u{XXXX}` part of a \u{XXXX} escape, with one
+// to six hexadecimal digits naming a Unicode code point.
+func (l *Lexer) readUnicodeEscape(start token.Position, out *strings.Builder) {
+	l.readChar()
+	if l.ch != '{' {
+		l.error(token.Span{Start: start, End: l.pos()}, "\\u must be followed by {hex digits}")
+		out.WriteString(l.input[start.Offset:l.position])
+		return
+	}
+	l.readChar()
+	digits := l.position
+	var r rune
+	for isHexDigit(l.ch) {
+		r = r*16 + rune(hexValue(l.ch))
+		if r > utf8.MaxRune {
+			r = utf8.MaxRune + 1
+		}
+		l.readChar()
+	}
+	n := l.position - digits
+	if l.ch != '}' {
+		l.error(token.Span{Start: start, End: l.pos()}, "unterminated \\u{...} escape")
+		out.WriteString(l.input[start.Offset:l.position])
+		return
+	}
+	l.readChar()
+	span := token.Span{Start: start, End: l.pos()}
+	switch {
+	case n == 0 || n > 6:
+		l.error(span, "\\u{...} escape must have between 1 and 6 hex digits")
+	case !utf8.ValidRune(r):
+		l.error(span, fmt.Sprintf("%s is not a valid Unicode code point", l.input[start.Offset:l.position]))
+	default:
+		out.WriteRune(r)
+		return
+	}
+	out.WriteString(l.input[start.Offset:l.position])
+}
+
 func AssignToken(tokenType token.TokenType, literal byte) token.Token {
 	return token.Token{Type: tokenType, Literal: string(literal)}
 }
 
 func New(input string) *Lexer {
+	return NewFile("", input)
+}
+
+// NewFile returns a lexer whose token positions carry filename.
+func NewFile(filename, input string) *Lexer {
 	l := &Lexer{
-		input: input,
+		filename: filename,
+		input:    input,
+		line:     1,
 	}
 	l.readChar()
+	l.skipShebang()
 	return l
 }
 
+// skipShebang skips a "#!" line at the very start of the input, so that
+// scripts can be made executable on Unix.
+func (l *Lexer) skipShebang() {
+	if !strings.HasPrefix(l.input, "#!") {
+		return
+	}
+	for l.position < len(l.input) && l.ch != '\n' {
+		l.readChar()
+	}
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
@@ -134,3 +486,22 @@ func isLetter(ch byte) bool {
 func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+func isDigitOrUnderscore(ch byte) bool {
+	return isDigit(ch) || ch == '_'
+}
+
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func hexValue(ch byte) int {
+	switch {
+	case isDigit(ch):
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'f':
+		return int(ch-'a') + 10
+	default:
+		return int(ch-'A') + 10
+	}
+}
@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/dawkaka/go-interpreter/token"
@@ -22,6 +23,12 @@ func TestNextToken(t *testing.T) {
               }
 			  10 == 10;
               10 != 9;
+              [1, 2];
+              {"foo": "bar"}
+              a <= b >= c % d && e || f;
+              a & b | c ^ ~d << e >> f;
+              a ** b * c;
+              3.14 1e-9 2.5E+3 7;
 			  `
 
 	tests := []struct {
@@ -101,6 +108,53 @@ func TestNextToken(t *testing.T) {
 		{token.NOT_EQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "foo"},
+		{token.COLON, ":"},
+		{token.STRING, "bar"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "d"},
+		{token.AND, "&&"},
+		{token.IDENT, "e"},
+		{token.OR, "||"},
+		{token.IDENT, "f"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "b"},
+		{token.PIPE, "|"},
+		{token.IDENT, "c"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.IDENT, "d"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.IDENT, "e"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.IDENT, "f"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.POWER, "**"},
+		{token.IDENT, "b"},
+		{token.ASTERISK, "*"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E+3"},
+		{token.INT, "7"},
+		{token.SEMICOLON, ";"},
 		{token.EOF, ""},
 	}
 
@@ -116,3 +170,182 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 10;\n  x != 5\n"
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedStart token.Position
+		expectedEnd   token.Position
+	}{
+		{token.LET, token.Position{Filename: "main.mk", Offset: 0, Line: 1, Column: 1}, token.Position{Filename: "main.mk", Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Filename: "main.mk", Offset: 4, Line: 1, Column: 5}, token.Position{Filename: "main.mk", Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Filename: "main.mk", Offset: 6, Line: 1, Column: 7}, token.Position{Filename: "main.mk", Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Filename: "main.mk", Offset: 8, Line: 1, Column: 9}, token.Position{Filename: "main.mk", Offset: 10, Line: 1, Column: 11}},
+		{token.SEMICOLON, token.Position{Filename: "main.mk", Offset: 10, Line: 1, Column: 11}, token.Position{Filename: "main.mk", Offset: 11, Line: 1, Column: 12}},
+		{token.IDENT, token.Position{Filename: "main.mk", Offset: 14, Line: 2, Column: 3}, token.Position{Filename: "main.mk", Offset: 15, Line: 2, Column: 4}},
+		{token.NOT_EQ, token.Position{Filename: "main.mk", Offset: 16, Line: 2, Column: 5}, token.Position{Filename: "main.mk", Offset: 18, Line: 2, Column: 7}},
+		{token.INT, token.Position{Filename: "main.mk", Offset: 19, Line: 2, Column: 8}, token.Position{Filename: "main.mk", Offset: 20, Line: 2, Column: 9}},
+		{token.EOF, token.Position{Filename: "main.mk", Offset: 21, Line: 3, Column: 1}, token.Position{Filename: "main.mk", Offset: 21, Line: 3, Column: 1}},
+		{token.EOF, token.Position{Filename: "main.mk", Offset: 21, Line: 3, Column: 1}, token.Position{Filename: "main.mk", Offset: 21, Line: 3, Column: 1}},
+	}
+
+	l := NewFile("main.mk", input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] wrong type; expected:[%q] but got: [%q]", i, tt.expectedType, tok.Type)
+		}
+		if tok.Start != tt.expectedStart {
+			t.Errorf("tests[%d] wrong start; expected:[%+v] but got: [%+v]", i, tt.expectedStart, tok.Start)
+		}
+		if tok.End != tt.expectedEnd {
+			t.Errorf("tests[%d] wrong end; expected:[%+v] but got: [%+v]", i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedErrors  []string
+	}{
+		{`"foobar"`, token.STRING, "foobar", nil},
+		{`"foo bar"`, token.STRING, "foo bar", nil},
+		{`""`, token.STRING, "", nil},
+		{`"a\nb\tc"`, token.STRING, "a\nb\tc", nil},
+		{`"say \"hi\""`, token.STRING, `say "hi"`, nil},
+		{`"back\\slash"`, token.STRING, `back\slash`, nil},
+		{`"\u{48}\u{e9}\u{1F600}"`, token.STRING, "Hé😀", nil},
+		{`"\q"`, token.STRING, `\q`, []string{`1:2: unknown escape sequence \q`}},
+		{`"\u{}"`, token.STRING, `\u{}`, []string{`1:2: \u{...} escape must have between 1 and 6 hex digits`}},
+		{`"\u{D800}"`, token.STRING, `\u{D800}`, []string{`1:2: \u{D800} is not a valid Unicode code point`}},
+		{`"\u41"`, token.STRING, `\u41`, []string{`1:2: \u must be followed by {hex digits}`}},
+		{`"open`, token.ILLEGAL, `"open`, []string{`1:1: unterminated string literal`}},
+	}
+
+	for _, tt := range tests {
+		var errors []string
+		l := New(tt.input)
+		l.Error = func(span token.Span, msg string) {
+			errors = append(errors, span.Start.String()+": "+msg)
+		}
+
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Errorf("input %s: wrong type; expected:[%q] but got: [%q]", tt.input, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("input %s: wrong literal; expected:[%q] but got: [%q]", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.End.Offset != len(tt.input) {
+			t.Errorf("input %s: token should end at %d, got %d", tt.input, len(tt.input), tok.End.Offset)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("input %s: expected EOF after string, got %q", tt.input, next.Type)
+		}
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("input %s: expected errors %q, got %q", tt.input, tt.expectedErrors, errors)
+			continue
+		}
+		for i, want := range tt.expectedErrors {
+			if errors[i] != want {
+				t.Errorf("input %s: wrong error; expected:[%s] but got: [%s]", tt.input, want, errors[i])
+			}
+		}
+	}
+}
+
+func TestIllegalCharacter(t *testing.T) {
+	var msg string
+	l := New("a @ b")
+	l.Error = func(span token.Span, m string) { msg = span.Start.String() + ": " + m }
+
+	expected := []token.TokenType{token.IDENT, token.ILLEGAL, token.IDENT, token.EOF}
+	for i, want := range expected {
+		if tok := l.NextToken(); tok.Type != want {
+			t.Fatalf("tests[%d] wrong type; expected:[%q] but got: [%q]", i, want, tok.Type)
+		}
+	}
+	if msg != `1:3: illegal character '@'` {
+		t.Errorf("wrong error. got=%q", msg)
+	}
+}
+
+func TestNumberForms(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+		errors   []string
+	}{
+		{"5.", []token.Token{{Type: token.INT, Literal: "5"}, {Type: token.ILLEGAL, Literal: "."}}, []string{"1:2: illegal character '.'"}},
+		{".5", []token.Token{{Type: token.ILLEGAL, Literal: "."}, {Type: token.INT, Literal: "5"}}, []string{"1:1: illegal character '.'"}},
+		{"1e", []token.Token{{Type: token.ILLEGAL, Literal: "1e"}}, []string{"1:1: exponent has no digits"}},
+		{"1.5e+", []token.Token{{Type: token.ILLEGAL, Literal: "1.5e+"}}, []string{"1:1: exponent has no digits"}},
+		{"0xFF", []token.Token{{Type: token.INT, Literal: "0xFF"}}, nil},
+		{"0Xdead_BEEF", []token.Token{{Type: token.INT, Literal: "0Xdead_BEEF"}}, nil},
+		{"0o755", []token.Token{{Type: token.INT, Literal: "0o755"}}, nil},
+		{"0b1010", []token.Token{{Type: token.INT, Literal: "0b1010"}}, nil},
+		{"1_000_000", []token.Token{{Type: token.INT, Literal: "1_000_000"}}, nil},
+		{"1_000.000_1e1_0", []token.Token{{Type: token.FLOAT, Literal: "1_000.000_1e1_0"}}, nil},
+		{"0", []token.Token{{Type: token.INT, Literal: "0"}}, nil},
+		{"0.5", []token.Token{{Type: token.FLOAT, Literal: "0.5"}}, nil},
+		{"0x", []token.Token{{Type: token.ILLEGAL, Literal: "0x"}}, []string{"1:1: hexadecimal literal has no digits"}},
+		{"0b_", []token.Token{{Type: token.ILLEGAL, Literal: "0b_"}}, []string{"1:1: binary literal has no digits"}},
+		{"0xFG", []token.Token{{Type: token.ILLEGAL, Literal: "0xFG"}}, []string{"1:1: invalid digit 'G' in hexadecimal literal"}},
+		{"0o78", []token.Token{{Type: token.ILLEGAL, Literal: "0o78"}}, []string{"1:1: invalid digit '8' in octal literal"}},
+		{"0b102", []token.Token{{Type: token.ILLEGAL, Literal: "0b102"}}, []string{"1:1: invalid digit '2' in binary literal"}},
+		{"1__0", []token.Token{{Type: token.ILLEGAL, Literal: "1__0"}}, []string{"1:1: '_' must separate successive digits"}},
+		{"1_", []token.Token{{Type: token.ILLEGAL, Literal: "1_"}}, []string{"1:1: '_' must separate successive digits"}},
+		{"0x_1", []token.Token{{Type: token.ILLEGAL, Literal: "0x_1"}}, []string{"1:1: '_' must separate successive digits"}},
+		{"1_.5", []token.Token{{Type: token.ILLEGAL, Literal: "1_.5"}}, []string{"1:1: '_' must separate successive digits"}},
+		{"1e_5", []token.Token{{Type: token.ILLEGAL, Literal: "1e_5"}}, []string{"1:1: '_' must separate successive digits"}},
+		{"0755", []token.Token{{Type: token.ILLEGAL, Literal: "0755"}}, []string{"1:1: integer 0755 has a leading zero; write octal numbers as 0o755"}},
+		{"00", []token.Token{{Type: token.ILLEGAL, Literal: "00"}}, []string{"1:1: integer 00 has a leading zero; write zero as 0"}},
+		{"0_0", []token.Token{{Type: token.ILLEGAL, Literal: "0_0"}}, []string{"1:1: integer 0_0 has a leading zero; write zero as 0"}},
+		{"0.0", []token.Token{{Type: token.FLOAT, Literal: "0.0"}}, nil},
+	}
+
+	for _, tt := range tests {
+		var errors []string
+		l := New(tt.input)
+		l.Error = func(span token.Span, msg string) {
+			errors = append(errors, span.Start.String()+": "+msg)
+		}
+		for i, want := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != want.Type || tok.Literal != want.Literal {
+				t.Errorf("input %s: token %d: expected %q %q, got %q %q", tt.input, i, want.Type, want.Literal, tok.Type, tok.Literal)
+			}
+		}
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("input %s: expected EOF, got %q", tt.input, tok.Type)
+		}
+		if strings.Join(errors, "\n") != strings.Join(tt.errors, "\n") {
+			t.Errorf("input %s: expected errors %q, got %q", tt.input, tt.errors, errors)
+		}
+	}
+}
+
+func TestShebang(t *testing.T) {
+	l := NewFile("s.mk", "#!/usr/bin/env monkey\nlet x;")
+
+	tok := l.NextToken()
+	if tok.Type != token.LET {
+		t.Fatalf("expected LET after the shebang, got %q", tok.Type)
+	}
+	if tok.Start.String() != "s.mk:2:1" {
+		t.Errorf("wrong position. got=%s", tok.Start)
+	}
+
+	l = New("let x; #!not a shebang")
+	l.Error = func(span token.Span, msg string) {}
+	for _, want := range []token.TokenType{token.LET, token.IDENT, token.SEMICOLON, token.ILLEGAL} {
+		if tok := l.NextToken(); tok.Type != want {
+			t.Fatalf("expected %q, got %q", want, tok.Type)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package lineedit
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// DefaultHistorySize is the number of lines a History keeps by default.
+const DefaultHistorySize = 1000
+
+// History is the list of lines entered so far, oldest first. If it was
+// opened with a file, every added line is also appended to that file.
+type History struct {
+	// Max is the number of lines kept in memory.
+	Max int
+
+	lines []string
+	path  string
+}
+
+// OpenHistory returns a History that loads and saves lines in the file at
+// path. A missing file is not an error; it is created by the first Add.
+func OpenHistory(path string) (*History, error) {
+	h := &History{Max: DefaultHistorySize, path: path}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	read := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.add(scanner.Text())
+		read++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// Add only ever appends to the file, so it is cut back to the lines kept
+	// in memory once it has grown well beyond them.
+	if read > 2*h.Max {
+		err = os.WriteFile(path, []byte(strings.Join(h.lines, "\n")+"\n"), 0o600)
+	}
+	return h, err
+}
+
+// Lines returns the lines in the history, oldest first.
+func (h *History) Lines() []string {
+	return h.lines
+}
+
+// Add appends line to the history unless it is blank or repeats the latest
+// line.
+func (h *History) Add(line string) error {
+	if !h.add(line) || h.path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (h *History) add(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return false
+	}
+	if n := len(h.lines); n > 0 && h.lines[n-1] == line {
+		return false
+	}
+	h.lines = append(h.lines, line)
+	max := h.Max
+	if max <= 0 {
+		max = DefaultHistorySize
+	}
+	if len(h.lines) > max {
+		h.lines = h.lines[len(h.lines)-max:]
+	}
+	return true
+}
+
+// search returns the index of the latest line at or before from that
+// contains query, or -1.
+func (h *History) search(query string, from int) int {
+	if from >= len(h.lines) {
+		from = len(h.lines) - 1
+	}
+	for i := from; i >= 0; i-- {
+		if strings.Contains(h.lines[i], query) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,397 @@
+// Package lineedit reads lines from a terminal with cursor movement,
+// history and tab completion. It understands the usual Emacs-style keys:
+//
+//	Left, Right, Ctrl-B, Ctrl-F   move the cursor
+//	Home, End, Ctrl-A, Ctrl-E     move to the start or end of the line
+//	Up, Down, Ctrl-P, Ctrl-N      step through the history
+//	Ctrl-R                        search the history backwards
+//	Backspace, Delete, Ctrl-D     delete a character
+//	Ctrl-K, Ctrl-U, Ctrl-W        delete to the end, to the start, or a word
+//	Tab                           complete the word before the cursor
+//	Ctrl-L                        clear the screen
+//	Ctrl-C                        abandon the line
+//
+// Lines are assumed to fit on one row of the terminal.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl-C.
+var ErrInterrupted = errors.New("lineedit: interrupted")
+
+// Completer returns the possible completions of prefix, the word before
+// the cursor.
+type Completer func(prefix string) []string
+
+type Editor struct {
+	History  *History
+	Complete Completer
+
+	in  *bufio.Reader
+	out io.Writer
+	// fd is the terminal put in raw mode while a line is read, or -1 if in
+	// is not a terminal.
+	fd int
+}
+
+// New returns an editor reading keys from in and drawing on out. If in is a
+// terminal it is switched to raw mode while ReadLine runs.
+func New(in io.Reader, out io.Writer) *Editor {
+	e := &Editor{History: &History{}, in: bufio.NewReader(in), out: out, fd: -1}
+	if f, ok := in.(*os.File); ok && isTerminal(int(f.Fd())) {
+		e.fd = int(f.Fd())
+	}
+	return e
+}
+
+// IsTerminal reports whether r is a terminal that an Editor can drive.
+func IsTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && isTerminal(int(f.Fd()))
+}
+
+func ctrl(r rune) rune { return r & 0x1f }
+
+const (
+	keyEscape    = 0x1b
+	keyBackspace = 0x7f
+)
+
+// ReadLine shows prompt and returns the line entered, which is added to the
+// history. It returns io.EOF if Ctrl-D is pressed on an empty line and
+// ErrInterrupted on Ctrl-C.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.fd >= 0 {
+		state, err := makeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		defer restore(e.fd, state)
+	}
+
+	l := &line{e: e, prompt: prompt, hist: len(e.History.lines)}
+	l.refresh()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err == io.EOF && len(l.buf) > 0 {
+			r, err = '\r', nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			return l.accept(), nil
+		case ctrl('C'):
+			io.WriteString(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case ctrl('D'):
+			if len(l.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			l.deleteForward()
+		case ctrl('A'):
+			l.pos = 0
+		case ctrl('E'):
+			l.pos = len(l.buf)
+		case ctrl('B'):
+			l.moveLeft()
+		case ctrl('F'):
+			l.moveRight()
+		case ctrl('H'), keyBackspace:
+			l.deleteBackward()
+		case ctrl('K'):
+			l.buf = l.buf[:l.pos]
+		case ctrl('U'):
+			l.buf = append([]rune{}, l.buf[l.pos:]...)
+			l.pos = 0
+		case ctrl('W'):
+			l.deleteWord()
+		case ctrl('P'):
+			l.historyPrev()
+		case ctrl('N'):
+			l.historyNext()
+		case ctrl('L'):
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case ctrl('R'):
+			submit, err := l.search()
+			if err != nil {
+				return "", err
+			}
+			if submit {
+				return l.accept(), nil
+			}
+		case '\t':
+			l.complete()
+		case keyEscape:
+			if err := l.escape(); err != nil {
+				return "", err
+			}
+		default:
+			if unicode.IsPrint(r) {
+				l.insert(r)
+			}
+		}
+		l.refresh()
+	}
+}
+
+// line is the state of a line being edited.
+type line struct {
+	e      *Editor
+	prompt string
+	buf    []rune
+	pos    int
+
+	// hist is the index of the history entry shown, or len(history) for the
+	// line being typed, which is kept in saved while the history is browsed.
+	hist  int
+	saved []rune
+}
+
+func (l *line) accept() string {
+	io.WriteString(l.e.out, "\r\n")
+	s := string(l.buf)
+	l.e.History.Add(s)
+	return s
+}
+
+func (l *line) refresh() {
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(l.prompt)
+	b.WriteString(string(l.buf))
+	b.WriteString("\x1b[K")
+	if n := len(l.buf) - l.pos; n > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", n)
+	}
+	io.WriteString(l.e.out, b.String())
+}
+
+func (l *line) set(s string) {
+	l.buf = []rune(s)
+	l.pos = len(l.buf)
+}
+
+func (l *line) insert(rs ...rune) {
+	buf := make([]rune, 0, len(l.buf)+len(rs))
+	buf = append(buf, l.buf[:l.pos]...)
+	buf = append(buf, rs...)
+	l.buf = append(buf, l.buf[l.pos:]...)
+	l.pos += len(rs)
+}
+
+func (l *line) moveLeft() {
+	if l.pos > 0 {
+		l.pos--
+	}
+}
+
+func (l *line) moveRight() {
+	if l.pos < len(l.buf) {
+		l.pos++
+	}
+}
+
+func (l *line) deleteBackward() {
+	if l.pos > 0 {
+		l.buf = append(l.buf[:l.pos-1], l.buf[l.pos:]...)
+		l.pos--
+	}
+}
+
+func (l *line) deleteForward() {
+	if l.pos < len(l.buf) {
+		l.buf = append(l.buf[:l.pos], l.buf[l.pos+1:]...)
+	}
+}
+
+// deleteWord deletes the word before the cursor and any spaces after it.
+func (l *line) deleteWord() {
+	start := l.pos
+	for start > 0 && l.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && l.buf[start-1] != ' ' {
+		start--
+	}
+	l.buf = append(l.buf[:start], l.buf[l.pos:]...)
+	l.pos = start
+}
+
+func (l *line) historyPrev() {
+	if l.hist == 0 {
+		return
+	}
+	if l.hist == len(l.e.History.lines) {
+		l.saved = l.buf
+	}
+	l.hist--
+	l.set(l.e.History.lines[l.hist])
+}
+
+func (l *line) historyNext() {
+	lines := l.e.History.lines
+	if l.hist >= len(lines) {
+		return
+	}
+	l.hist++
+	if l.hist == len(lines) {
+		l.buf = l.saved
+		l.pos = len(l.buf)
+		return
+	}
+	l.set(lines[l.hist])
+}
+
+// escape handles the escape sequence sent by a cursor or editing key.
+func (l *line) escape() error {
+	next, _, err := l.e.in.ReadRune()
+	if err != nil {
+		return err
+	}
+	if next != '[' && next != 'O' {
+		return nil
+	}
+	var seq []rune
+	for {
+		r, _, err := l.e.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		seq = append(seq, r)
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+	}
+
+	switch string(seq) {
+	case "A":
+		l.historyPrev()
+	case "B":
+		l.historyNext()
+	case "C":
+		l.moveRight()
+	case "D":
+		l.moveLeft()
+	case "H", "1~", "7~":
+		l.pos = 0
+	case "F", "4~", "8~":
+		l.pos = len(l.buf)
+	case "3~":
+		l.deleteForward()
+	}
+	return nil
+}
+
+// search runs a reverse incremental search of the history. Enter submits
+// the line found, Ctrl-G or Ctrl-C leaves the line as it was, and any other
+// key stops the search to edit the line found.
+func (l *line) search() (submit bool, err error) {
+	history := l.e.History
+	var query []rune
+	match := -1
+	from := len(history.lines) - 1
+
+	for {
+		found := ""
+		if match >= 0 {
+			found = history.lines[match]
+		}
+		fmt.Fprintf(l.e.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), found)
+
+		r, _, err := l.e.in.ReadRune()
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case r == ctrl('R'):
+			if match > 0 {
+				if m := history.search(string(query), match-1); m >= 0 {
+					match = m
+				}
+			}
+			continue
+		case r == ctrl('H') || r == keyBackspace:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+		case r == ctrl('G') || r == ctrl('C'):
+			return false, nil
+		case unicode.IsPrint(r):
+			query = append(query, r)
+			if match >= 0 {
+				from = match
+			}
+		default:
+			if match >= 0 {
+				l.set(history.lines[match])
+			}
+			if r == '\r' || r == '\n' {
+				return true, nil
+			}
+			if r == keyEscape {
+				return false, l.escape()
+			}
+			return false, nil
+		}
+		match = -1
+		if len(query) > 0 {
+			match = history.search(string(query), from)
+		}
+	}
+}
+
+// complete completes the word before the cursor as far as all candidates
+// agree, and lists the candidates if that adds nothing.
+func (l *line) complete() {
+	if l.e.Complete == nil {
+		return
+	}
+	start := l.pos
+	for start > 0 && isWordRune(l.buf[start-1]) {
+		start--
+	}
+	prefix := string(l.buf[start:l.pos])
+	candidates := l.e.Complete(prefix)
+	if len(candidates) == 0 {
+		return
+	}
+
+	common := commonPrefix(candidates)
+	if len(common) > len(prefix) && strings.HasPrefix(common, prefix) {
+		l.insert([]rune(common[len(prefix):])...)
+		return
+	}
+	if len(candidates) > 1 {
+		sort.Strings(candidates)
+		fmt.Fprintf(l.e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
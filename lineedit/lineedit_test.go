@@ -0,0 +1,166 @@
+package lineedit
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readLines(t *testing.T, e *Editor) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := e.ReadLine("> ")
+		if err == io.EOF {
+			return lines
+		}
+		if err == ErrInterrupted {
+			lines = append(lines, "<interrupted>")
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ReadLine: %s", err)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestEditing(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"abc\r", "abc"},
+		{"abc\x1b[D\x1b[DX\r", "aXbc"},
+		{"abc\x01X\x05Y\r", "XabcY"},
+		{"abc\x7f\x7f\r", "a"},
+		{"abc\x02\x02\x04\r", "ac"},
+		{"abc\x1b[H\x1b[3~\r", "bc"},
+		{"hello world\x02\x02\x0b\r", "hello wor"},
+		{"hello world\x02\x02\x15\r", "ld"},
+		{"let x = 1\x17\x17\r", "let x "},
+		{"abc\x1b[1~Z\x1b[4~!\r", "Zabc!"},
+		{"日本\x1b[D語\r", "日語本"},
+	}
+
+	for _, tt := range tests {
+		e := New(strings.NewReader(tt.keys), io.Discard)
+		line, err := e.ReadLine("> ")
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", tt.keys, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("%q: got %q, want %q", tt.keys, line, tt.expected)
+		}
+	}
+}
+
+func TestControlKeys(t *testing.T) {
+	e := New(strings.NewReader("one\rtwo\x03three"), io.Discard)
+	got := readLines(t, e)
+	want := []string{"one", "<interrupted>", "three"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	keys := strings.Join([]string{
+		"first\r",
+		"second\r",
+		"second\r",
+		"\x1b[A\x1b[A!\r",       // up twice: first
+		"draft\x1b[A\x1b[B\r",   // up then down restores the draft
+		"\x12sec\r",             // search
+		"\x12fi\x12\x1b[C?\r",   // search further back, then edit the match
+		"\x12nothing\x07kept\r", // cancelled search
+	}, "")
+	e := New(strings.NewReader(keys), io.Discard)
+	got := readLines(t, e)
+	want := []string{"first", "second", "second", "first!", "draft", "second", "first?", "kept"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	history := []string{"first", "second", "first!", "draft", "second", "first?", "kept"}
+	if !reflect.DeepEqual(e.History.Lines(), history) {
+		t.Errorf("history %q, want %q", e.History.Lines(), history)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h, err := OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(strings.NewReader("let a = 1;\r\rlet b = 2;\r"), io.Discard)
+	e.History = h
+	readLines(t, e)
+
+	h, err = OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"let a = 1;", "let b = 2;"}; !reflect.DeepEqual(h.Lines(), want) {
+		t.Errorf("history %q, want %q", h.Lines(), want)
+	}
+
+	h.Max = 2
+	for _, line := range []string{"x", "y", "z"} {
+		h.Add(line)
+	}
+	if want := []string{"y", "z"}; !reflect.DeepEqual(h.Lines(), want) {
+		t.Errorf("history %q, want %q", h.Lines(), want)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("history file not written: %s", err)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	words := []string{"let", "len", "last", "puts", "push"}
+	complete := func(prefix string) []string {
+		var out []string
+		for _, w := range words {
+			if strings.HasPrefix(w, prefix) {
+				out = append(out, w)
+			}
+		}
+		return out
+	}
+
+	tests := []struct {
+		keys     string
+		expected string
+		listed   string
+	}{
+		{"las\t(x)\r", "last(x)", ""},
+		{"pu\ts\r", "pus", "push  puts"},
+		{"l\t\r", "l", "last  len  let"},
+		{"le\t\r", "le", "len  let"},
+		{"x + pu\tt\x1b[D\x1b[D\r", "x + put", "push  puts"},
+		{"zz\t\r", "zz", ""},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		e := New(strings.NewReader(tt.keys), &out)
+		e.Complete = complete
+		line, err := e.ReadLine("> ")
+		if err != nil {
+			t.Fatalf("%q: %s", tt.keys, err)
+		}
+		if line != tt.expected {
+			t.Errorf("%q: got %q, want %q", tt.keys, line, tt.expected)
+		}
+		if tt.listed != "" && !strings.Contains(out.String(), "\r\n"+tt.listed+"\r\n") {
+			t.Errorf("%q: candidates %q not listed in %q", tt.keys, tt.listed, out.String())
+		}
+	}
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package lineedit
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+type termState struct {
+	termios syscall.Termios
+}
+
+func getState(fd int) (*termState, error) {
+	var s termState
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCGETS, uintptr(unsafe.Pointer(&s.termios))); errno != 0 {
+		return nil, errno
+	}
+	return &s, nil
+}
+
+func setState(fd int, s *termState) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCSETS, uintptr(unsafe.Pointer(&s.termios))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func isTerminal(fd int) bool {
+	_, err := getState(fd)
+	return err == nil
+}
+
+// makeRaw turns off echo, line buffering and signal keys on fd, so that
+// every key press can be read as it happens, and returns the previous state.
+func makeRaw(fd int) (*termState, error) {
+	old, err := getState(fd)
+	if err != nil {
+		return nil, err
+	}
+	raw := *old
+	raw.termios.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+	raw.termios.Oflag &^= syscall.OPOST
+	raw.termios.Cflag |= syscall.CS8
+	raw.termios.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	raw.termios.Cc[syscall.VMIN] = 1
+	raw.termios.Cc[syscall.VTIME] = 0
+	if err := setState(fd, &raw); err != nil {
+		return nil, err
+	}
+	return old, nil
+}
+
+func restore(fd int, s *termState) error {
+	return setState(fd, s)
+}
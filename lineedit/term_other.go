@@ -0,0 +1,18 @@
+//go:build !linux
+
+package lineedit
+
+import "errors"
+
+// Raw mode is only implemented for Linux. Elsewhere no file counts as a
+// terminal, so callers fall back to reading plain lines.
+
+type termState struct{}
+
+func isTerminal(fd int) bool { return false }
+
+func makeRaw(fd int) (*termState, error) {
+	return nil, errors.New("lineedit: raw mode is not supported on this platform")
+}
+
+func restore(fd int, s *termState) error { return nil }
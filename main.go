@@ -1,20 +1,139 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 
+	"github.com/dawkaka/go-interpreter/monkey"
+	"github.com/dawkaka/go-interpreter/object"
 	"github.com/dawkaka/go-interpreter/repl"
+	"github.com/dawkaka/go-interpreter/report"
 )
 
+// Exit codes, so that callers can tell a broken script from one that failed
+// while running.
+const (
+	exitOK           = 0
+	exitRuntimeError = 1
+	exitUsage        = 2
+	exitParseError   = 3
+)
+
+const usage = `usage:
+  monkey                  start the REPL, or run the program piped to stdin
+  monkey run FILE         run the script in FILE ("-" for stdin)
+  monkey -e PROGRAM       run PROGRAM and print its value
+`
+
 func main() {
-	user, err := user.Current()
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	program := flags.String("e", "", "run `PROGRAM` and print its value")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	args = flags.Args()
+
+	evalFlag := false
+	flags.Visit(func(f *flag.Flag) { evalFlag = evalFlag || f.Name == "e" })
+
+	switch {
+	case evalFlag:
+		if len(args) != 0 {
+			return usageError(stderr, "-e cannot be combined with other arguments")
+		}
+		return runSource(stdout, stderr, "<-e>", *program, true)
+	case len(args) == 0:
+		if isTerminal(stdin) {
+			greet(stdout)
+			repl.Start(stdin, stdout)
+			return exitOK
+		}
+		return runFile(stdin, stdout, stderr, "-")
+	case args[0] == "run":
+		if len(args) != 2 {
+			return usageError(stderr, "run takes exactly one file")
+		}
+		return runFile(stdin, stdout, stderr, args[1])
+	default:
+		return usageError(stderr, fmt.Sprintf("unknown command %q", args[0]))
+	}
+}
+
+func usageError(stderr io.Writer, msg string) int {
+	fmt.Fprintf(stderr, "monkey: %s\n%s", msg, usage)
+	return exitUsage
+}
+
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && report.IsTerminal(f)
+}
+
+func greet(out io.Writer) {
+	name := "there"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	fmt.Fprintf(out, "Hello %s, this is the monkey programming language!\n", name)
+	fmt.Fprintln(out, "Feel free to key in your commands")
+}
+
+// runFile runs the script in filename, or the one on stdin if filename is "-".
+func runFile(stdin io.Reader, stdout, stderr io.Writer, filename string) int {
+	var src []byte
+	var err error
+	if filename == "-" {
+		filename = "<stdin>"
+		src, err = io.ReadAll(stdin)
+	} else {
+		src, err = os.ReadFile(filename)
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return exitRuntimeError
+	}
+	return runSource(stdout, stderr, filename, string(src), false)
+}
+
+// runSource runs src, reporting errors on stderr. With printResult set the
+// value of the program is written to stdout unless it is null.
+func runSource(stdout, stderr io.Writer, filename, src string, printResult bool) int {
+	renderer := report.NewRenderer(stderr)
+	interp := monkey.New(monkey.WithFilename(filename), monkey.WithStdout(stdout))
+	v, err := interp.Eval(context.Background(), src)
+
+	var parseErr *monkey.ParseError
+	var runtimeErr *monkey.RuntimeError
+	switch {
+	case errors.As(err, &parseErr):
+		for _, d := range parseErr.Diagnostics {
+			renderer.Render(stderr, src, report.FromDiagnostic(d))
+		}
+		return exitParseError
+	case errors.As(err, &runtimeErr):
+		renderer.Render(stderr, src, report.FromRuntimeError(runtimeErr.Err))
+		return exitRuntimeError
+	case err != nil:
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return exitRuntimeError
 	}
 
-	fmt.Printf("Hello %s, this is the monkey programming language!\n", user.Username)
-	fmt.Println("Feel free to key in your commands")
-	repl.Start(os.Stdin, os.Stdout)
+	if printResult && v.Object() != object.NULL {
+		fmt.Fprintln(stdout, v)
+	}
+	return exitOK
 }
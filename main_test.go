@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.mk")
+	if err := os.WriteFile(script, []byte("#!/usr/bin/env monkey\nputs(len(\"abc\"));\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		stdin    string
+		code     int
+		stdout   string
+		inStderr string
+	}{
+		{[]string{"-e", "1 + 2"}, "", exitOK, "3\n", ""},
+		{[]string{"-e", "let x = 1;"}, "", exitOK, "", ""},
+		{[]string{"-e", "let x 1"}, "", exitParseError, "", "error[P0001]"},
+		{[]string{"-e", "1 + true"}, "", exitRuntimeError, "", "type mismatch"},
+		{[]string{"-e", "let f = fn(x) { f(x) }; f(1)"}, "", exitRuntimeError, "", "call depth limit exceeded (10000)"},
+		{[]string{"run", script}, "", exitOK, "3\n", ""},
+		{[]string{"run", "-"}, "puts(1)", exitOK, "1\n", ""},
+		{nil, `puts("piped"); -true`, exitRuntimeError, "piped\n", "<stdin>:1:16"},
+		{[]string{"run", filepath.Join(t.TempDir(), "missing.mk")}, "", exitRuntimeError, "", "no such file"},
+		{[]string{"run"}, "", exitUsage, "", "usage:"},
+		{[]string{"-e", "1", "extra"}, "", exitUsage, "", "usage:"},
+		{[]string{"frobnicate"}, "", exitUsage, "", `unknown command "frobnicate"`},
+		{[]string{"-x"}, "", exitUsage, "", "usage:"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+		if code != tt.code {
+			t.Errorf("%q: exit code %d, want %d (stderr %q)", tt.args, code, tt.code, stderr.String())
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%q: stdout %q, want %q", tt.args, stdout.String(), tt.stdout)
+		}
+		if !strings.Contains(stderr.String(), tt.inStderr) {
+			t.Errorf("%q: stderr %q does not contain %q", tt.args, stderr.String(), tt.inStderr)
+		}
+	}
+}
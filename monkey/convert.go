@@ -0,0 +1,138 @@
+package monkey
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/dawkaka/go-interpreter/object"
+)
+
+// ToObject converts a Go value to a language value:
+//
+//	nil                      NULL
+//	bool                     BOOLEAN
+//	signed and unsigned ints INTEGER
+//	float32 and float64      FLOAT
+//	string                   STRING
+//	slices and arrays        ARRAY
+//	maps                     HASH (keys must convert to INTEGER, BOOLEAN or STRING)
+//	object.BuiltinFunction   BUILTIN
+//
+// An object.Object is returned unchanged and pointers are followed.
+func ToObject(v interface{}) (object.Object, error) {
+	switch v := v.(type) {
+	case nil:
+		return object.NULL, nil
+	case object.Object:
+		return v, nil
+	case object.BuiltinFunction:
+		return &object.Builtin{Fn: v}, nil
+	case func(args ...object.Object) object.Object:
+		return &object.Builtin{Fn: v}, nil
+	}
+	return toObject(reflect.ValueOf(v))
+}
+
+func toObject(rv reflect.Value) (object.Object, error) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return object.TRUE, nil
+		}
+		return object.FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows INTEGER", u)
+		}
+		return &object.Integer{Value: int64(u)}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: rv.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: rv.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return &object.Array{Elements: []object.Object{}}, nil
+		}
+		elements := make([]object.Object, rv.Len())
+		for i := range elements {
+			el, err := toObject(rv.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			elements[i] = el
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		pairs := make(map[object.HashKey]object.HashPair, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := toObject(iter.Key())
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+			value, err := toObject(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			pairs[hashable.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+		return &object.Hash{Pairs: pairs}, nil
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return object.NULL, nil
+		}
+		if rv.CanInterface() {
+			return ToObject(rv.Elem().Interface())
+		}
+		return toObject(rv.Elem())
+	case reflect.Invalid:
+		return object.NULL, nil
+	}
+	if rv.CanInterface() {
+		if obj, ok := rv.Interface().(object.Object); ok {
+			return obj, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported Go type %s", rv.Type())
+}
+
+// FromObject converts a language value to Go. INTEGER becomes int64, FLOAT
+// float64, BOOLEAN bool, STRING string, NULL nil, ARRAY []interface{} and HASH
+// map[interface{}]interface{}. Other values, such as functions, are returned
+// unchanged.
+func FromObject(obj object.Object) interface{} {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value
+	case *object.Float:
+		return obj.Value
+	case *object.Boolean:
+		return obj.Value
+	case *object.String:
+		return obj.Value
+	case *object.Null:
+		return nil
+	case *object.Array:
+		elements := make([]interface{}, len(obj.Elements))
+		for i, el := range obj.Elements {
+			elements[i] = FromObject(el)
+		}
+		return elements
+	case *object.Hash:
+		m := make(map[interface{}]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			m[FromObject(pair.Key)] = FromObject(pair.Value)
+		}
+		return m
+	default:
+		return obj
+	}
+}
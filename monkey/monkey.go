@@ -0,0 +1,230 @@
+// Package monkey embeds the interpreter in Go programs.
+//
+//	interp := monkey.New()
+//	interp.Set("limit", 10)
+//	v, err := interp.Eval(ctx, `let double = fn(x) { x * 2 }; double(limit)`)
+//	// v.Interface() == int64(20)
+//
+// Bindings made by Set and by let statements are kept between calls to Eval.
+// An Interpreter is not safe for concurrent use.
+package monkey
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dawkaka/go-interpreter/evaluator"
+	"github.com/dawkaka/go-interpreter/lexer"
+	"github.com/dawkaka/go-interpreter/object"
+	"github.com/dawkaka/go-interpreter/parser"
+)
+
+type Interpreter struct {
+	// prelude holds the bindings that options make in place of builtins.
+	// Scripts see them through env but can only shadow them.
+	prelude  *object.Environment
+	env      *object.Environment
+	filename string
+	limits   object.Limits
+	stats    Stats
+}
+
+// Stats describes the work done by a call to Eval.
+type Stats struct {
+	// Steps is the number of AST nodes evaluated.
+	Steps int
+	// PeakMemory is the largest estimated number of bytes that strings,
+	// arrays, hashes, functions and environments used at once, including
+	// those kept in bindings from earlier calls.
+	PeakMemory int
+}
+
+// Option configures an Interpreter created by New.
+type Option func(*Interpreter)
+
+// WithFilename sets the file name used in the positions of errors.
+func WithFilename(name string) Option {
+	return func(i *Interpreter) { i.filename = name }
+}
+
+// WithMaxSteps limits each call to Eval to evaluating n AST nodes.
+func WithMaxSteps(n int) Option {
+	return func(i *Interpreter) { i.limits.MaxSteps = n }
+}
+
+// WithMaxCallDepth limits how deeply script functions may call each other,
+// in place of the default of object.DefaultMaxCallDepth.
+func WithMaxCallDepth(n int) Option {
+	return func(i *Interpreter) { i.limits.MaxCallDepth = n }
+}
+
+// WithMaxMemory limits the interpreter to about n bytes of memory in use at
+// once. The estimate counts strings, arrays, hashes, functions and the
+// environments of function calls. The quota covers the whole interpreter:
+// each call to Eval starts out charged for what the bindings made by Set
+// and by earlier calls keep alive.
+func WithMaxMemory(n int) Option {
+	return func(i *Interpreter) { i.limits.MaxMemory = n }
+}
+
+// WithStdout makes the puts builtin of the interpreter write to w instead of
+// os.Stdout. Like the other builtins, this puts is not returned by Get and
+// is only shadowed, not replaced, by a let statement.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) { i.prelude.Set("puts", object.NewPuts(w)) }
+}
+
+// WithGlobals binds each entry of globals as if by Set. It panics if a value
+// cannot be converted.
+func WithGlobals(globals map[string]interface{}) Option {
+	return func(i *Interpreter) {
+		for name, v := range globals {
+			if err := i.Set(name, v); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+func New(opts ...Option) *Interpreter {
+	prelude := object.NewEnvironment()
+	i := &Interpreter{
+		prelude: prelude,
+		env:     object.NewEnclosedEnvironment(prelude),
+		limits:  object.Limits{MaxCallDepth: object.DefaultMaxCallDepth},
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Eval parses and evaluates source and returns the value of its last
+// statement. Errors are a *ParseError or a *RuntimeError.
+//
+// Evaluation stops when ctx is done or a limit set by WithMaxSteps,
+// WithMaxCallDepth or WithMaxMemory is exceeded; the *RuntimeError then wraps a
+// *LimitExceeded, which errors.As can extract.
+func (i *Interpreter) Eval(ctx context.Context, source string) (Value, error) {
+	if err := ctx.Err(); err != nil {
+		return Value{}, limitError(source, &object.LimitExceeded{Kind: object.LimitContext, Cause: err})
+	}
+
+	p := parser.New(lexer.NewFile(i.filename, source))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		return Value{}, &ParseError{Source: source, Diagnostics: errs}
+	}
+
+	meter := object.NewMeter(ctx, i.limits)
+	i.env.SetMeter(meter)
+	defer func() {
+		i.env.SetMeter(nil)
+		i.stats = Stats{Steps: meter.Steps(), PeakMemory: meter.PeakMemory()}
+	}()
+	baseline, err := object.ReachableSize(ctx, i.env)
+	if err == nil {
+		err = meter.Alloc(baseline)
+	}
+	if err != nil {
+		return Value{}, limitError(source, err)
+	}
+	result := evaluator.Eval(program, i.env)
+	if errObj, ok := result.(*object.Error); ok {
+		return Value{}, &RuntimeError{Source: source, Err: errObj}
+	}
+	if result == nil {
+		result = object.NULL
+	}
+	return Value{obj: result}, nil
+}
+
+// limitError reports a limit exceeded outside of the evaluation of source.
+func limitError(source string, err error) *RuntimeError {
+	return &RuntimeError{Source: source, Err: &object.Error{Message: err.Error(), Err: err}}
+}
+
+// Stats returns the statistics of the last call to Eval that got as far as
+// evaluating its source.
+func (i *Interpreter) Stats() Stats {
+	return i.stats
+}
+
+// Set binds name to the conversion of v; see ToObject for the supported types.
+func (i *Interpreter) Set(name string, v interface{}) error {
+	obj, err := ToObject(v)
+	if err != nil {
+		return fmt.Errorf("monkey: set %s: %w", name, err)
+	}
+	if b, ok := obj.(*object.Builtin); ok && b.Name == "" {
+		obj = &object.Builtin{Name: name, Fn: b.Fn}
+	}
+	i.env.Set(name, obj)
+	return nil
+}
+
+// Get returns the value bound to name, if any. Builtins are not included.
+func (i *Interpreter) Get(name string) (Value, bool) {
+	obj, ok := i.env.Get(name)
+	if !ok {
+		return Value{}, false
+	}
+	if builtin, ok := i.prelude.Get(name); ok && builtin == obj {
+		return Value{}, false
+	}
+	return Value{obj: obj}, true
+}
+
+// Value is a language value returned to the host.
+type Value struct {
+	obj object.Object
+}
+
+// Object returns the underlying value, or nil for the zero Value.
+func (v Value) Object() object.Object { return v.obj }
+
+// Interface converts the value to Go; see FromObject.
+func (v Value) Interface() interface{} {
+	if v.obj == nil {
+		return nil
+	}
+	return FromObject(v.obj)
+}
+
+func (v Value) String() string {
+	if v.obj == nil {
+		return "<nil>"
+	}
+	return v.obj.Inspect()
+}
+
+// ParseError is returned by Eval when source does not parse.
+type ParseError struct {
+	Source      string
+	Diagnostics []*parser.Diagnostic
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// RuntimeError is returned by Eval when evaluation fails.
+type RuntimeError struct {
+	Source string
+	Err    *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Span.Start.String() + ": " + e.Err.Message
+}
+
+func (e *RuntimeError) Unwrap() error { return e.Err.Err }
+
+// LimitExceeded reports which limit stopped an evaluation.
+type LimitExceeded = object.LimitExceeded
@@ -0,0 +1,295 @@
+package monkey
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dawkaka/go-interpreter/object"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"5 + 5", int64(10)},
+		{"1.5 * 2", 3.0},
+		{"true == false", false},
+		{`"a" + "b"`, "ab"},
+		{"[1, true, \"x\"]", []interface{}{int64(1), true, "x"}},
+		{`{"a": 1, 2: false}`, map[interface{}]interface{}{"a": int64(1), int64(2): false}},
+		{"if (false) { 1 }", nil},
+		{"let x = 1;", nil},
+	}
+
+	for _, tt := range tests {
+		v, err := New().Eval(context.Background(), tt.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.input, err)
+			continue
+		}
+		if got := v.Interface(); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: got %#v, want %#v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	interp := New()
+	globals := map[string]interface{}{
+		"n":       uint8(3),
+		"names":   []string{"ann", "bob"},
+		"ages":    map[string]int{"ann": 30},
+		"flag":    true,
+		"nothing": nil,
+		"double": func(args ...object.Object) object.Object {
+			return &object.Integer{Value: 2 * args[0].(*object.Integer).Value}
+		},
+	}
+	for name, v := range globals {
+		if err := interp.Set(name, v); err != nil {
+			t.Fatalf("Set(%q): %s", name, err)
+		}
+	}
+
+	v, err := interp.Eval(context.Background(), `let total = double(n) + ages["ann"]; if (flag) { names[1] }`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v.Interface() != "bob" {
+		t.Errorf("got %#v, want %q", v.Interface(), "bob")
+	}
+
+	total, ok := interp.Get("total")
+	if !ok {
+		t.Fatalf("total not bound")
+	}
+	if total.Interface() != int64(36) {
+		t.Errorf("total = %#v, want 36", total.Interface())
+	}
+	if _, ok := interp.Get("len"); ok {
+		t.Errorf("Get returned a builtin")
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{uint64(1 << 63), "monkey: set v: 9223372036854775808 overflows INTEGER"},
+		{1 + 2i, "monkey: set v: unsupported Go type complex128"},
+		{map[float64]int{1.5: 1}, "monkey: set v: unusable as hash key: FLOAT"},
+		{map[[2]int]int{{1, 2}: 3}, "monkey: set v: unusable as hash key: ARRAY"},
+		{[]interface{}{1, struct{}{}}, "monkey: set v: index 1: unsupported Go type struct {}"},
+	}
+
+	for _, tt := range tests {
+		err := New().Set("v", tt.value)
+		if err == nil {
+			t.Errorf("Set(%#v): expected error", tt.value)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	interp := New(WithFilename("rules.mk"))
+
+	_, err := interp.Eval(context.Background(), "let x 5;")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected *ParseError, got %T (%v)", err, err)
+	}
+	if want := "rules.mk:1:7: expected next token to be =, got INT instead"; err.Error() != want {
+		t.Errorf("wrong error. want=%q, got=%q", want, err.Error())
+	}
+
+	_, err = interp.Eval(context.Background(), "1 + true")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected *RuntimeError, got %T (%v)", err, err)
+	}
+	if want := "rules.mk:1:1: type mismatch: INTEGER + BOOLEAN"; err.Error() != want {
+		t.Errorf("wrong error. want=%q, got=%q", want, err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = interp.Eval(ctx, "1")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	var limitErr *LimitExceeded
+	if !errors.As(err, &runtimeErr) || !errors.As(err, &limitErr) || limitErr.Kind != object.LimitContext {
+		t.Errorf("expected a *RuntimeError wrapping a context *LimitExceeded, got %T (%v)", err, err)
+	}
+}
+
+func TestEvalLimits(t *testing.T) {
+	loop := "let f = fn(n) { f(n + 1) }; f(0)"
+	tests := []struct {
+		interp   *Interpreter
+		timeout  time.Duration
+		expected object.LimitKind
+	}{
+		{New(WithMaxSteps(1000)), time.Minute, object.LimitSteps},
+		{New(WithMaxCallDepth(50)), time.Minute, object.LimitCallDepth},
+		{New(), time.Minute, object.LimitCallDepth},
+		{New(WithMaxCallDepth(1 << 30)), 10 * time.Millisecond, object.LimitContext},
+	}
+
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+		_, err := tt.interp.Eval(ctx, loop)
+		cancel()
+
+		var limitErr *LimitExceeded
+		if !errors.As(err, &limitErr) {
+			t.Errorf("expected *LimitExceeded, got %T (%v)", err, err)
+			continue
+		}
+		if limitErr.Kind != tt.expected {
+			t.Errorf("wrong limit. want=%s, got=%s", tt.expected, limitErr.Kind)
+		}
+	}
+
+	// The step limit applies to each call to Eval separately.
+	interp := New(WithMaxSteps(100))
+	for i := 0; i < 3; i++ {
+		if _, err := interp.Eval(context.Background(), "let x = 1 + 2 * 3;"); err != nil {
+			t.Fatalf("call %d: unexpected error: %s", i, err)
+		}
+	}
+}
+
+func TestMemoryQuotaAndStats(t *testing.T) {
+	grow := `let grow = fn(s, n) { if (n == 0) { len(s) } else { grow(s + s, n - 1) } };`
+
+	interp := New(WithMaxMemory(1 << 20))
+	_, err := interp.Eval(context.Background(), grow+`grow("ab", 40)`)
+	var limitErr *LimitExceeded
+	if !errors.As(err, &limitErr) || limitErr.Kind != object.LimitMemory {
+		t.Fatalf("expected memory LimitExceeded, got %v", err)
+	}
+	if peak := interp.Stats().PeakMemory; peak <= 1<<20 {
+		t.Errorf("peak memory %d should exceed the quota", peak)
+	}
+
+	v, err := interp.Eval(context.Background(), grow+`grow("ab", 10)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v.Interface() != int64(2048) {
+		t.Errorf("got %v, want 2048", v)
+	}
+	stats := interp.Stats()
+	if stats.PeakMemory < 2048 || stats.PeakMemory > 1<<20 {
+		t.Errorf("implausible peak memory %d", stats.PeakMemory)
+	}
+	if stats.Steps == 0 {
+		t.Errorf("no steps recorded")
+	}
+}
+
+func TestMemoryQuotaSpansCalls(t *testing.T) {
+	double := `let double = fn(s, n) { if (n == 0) { s } else { double(s + s, n - 1) } };`
+
+	// Values that are dropped do not count against later calls.
+	interp := New(WithMaxMemory(10000))
+	for i := 0; i < 20; i++ {
+		if _, err := interp.Eval(context.Background(), double+`len(double("ab", 10))`); err != nil {
+			t.Fatalf("call %d: unexpected error: %s", i, err)
+		}
+	}
+
+	// Values kept in bindings do.
+	var limitErr *LimitExceeded
+	for i := 0; i < 10 && limitErr == nil; i++ {
+		_, err := interp.Eval(context.Background(), fmt.Sprintf(`let s%c = double("ab", 10);`, 'a'+i))
+		if err != nil && !errors.As(err, &limitErr) {
+			t.Fatalf("call %d: unexpected error: %s", i, err)
+		}
+	}
+	if limitErr == nil || limitErr.Kind != object.LimitMemory {
+		t.Fatalf("bindings grew past the quota without an error")
+	}
+}
+
+func TestWithStdout(t *testing.T) {
+	var a, b bytes.Buffer
+	first := New(WithStdout(&a))
+	second := New(WithStdout(&b))
+	for _, run := range []struct {
+		interp *Interpreter
+		src    string
+	}{{first, `puts("one")`}, {second, `puts("two", 2)`}} {
+		if _, err := run.interp.Eval(context.Background(), run.src); err != nil {
+			t.Fatalf("%s: unexpected error: %s", run.src, err)
+		}
+	}
+	if a.String() != "one\n" || b.String() != "two\n2\n" {
+		t.Errorf("puts output mixed up: %q and %q", a.String(), b.String())
+	}
+	if _, ok := first.Get("puts"); ok {
+		t.Errorf("Get returned the puts builtin")
+	}
+
+	// A script can shadow puts like any other builtin.
+	if _, err := first.Eval(context.Background(), `let puts = fn(x) { x }; puts("three")`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v, ok := first.Get("puts"); !ok || v.Object().Type() != object.FUNCTION_OBJ {
+		t.Errorf("Get(%q) = %v, %t; want the script's function", "puts", v, ok)
+	}
+	if a.String() != "one\n" {
+		t.Errorf("shadowed puts still wrote: %q", a.String())
+	}
+}
+
+func TestSharedArraysAreAccountedQuickly(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Each return measures what the call keeps, which here is 32 levels of
+	// arrays that hold the level below twice.
+	interp := New(WithMaxSteps(10000))
+	nest := `let g = fn(a, n) { if (n == 0) { a } else { g([a, a], n - 1) } }; g(1, 32); 1`
+	if _, err := interp.Eval(ctx, nest); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The bindings are measured again at the start of every Eval.
+	interp = New()
+	src := "let a = [1];" + strings.Repeat("let a = [a, a];", 30)
+	for _, s := range []string{src, "1"} {
+		if _, err := interp.Eval(ctx, s); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("accounting for shared arrays took too long")
+	}
+}
+
+func TestDeepEvaluationStopsCleanly(t *testing.T) {
+	// Neither the call depth nor the nesting of the expression is past its
+	// limit, but together they would exhaust the Go stack.
+	nested := strings.Repeat("(1 + ", 240) + "f(n - 1)" + strings.Repeat(")", 240)
+	src := "let f = fn(n) { if (n == 0) { 0 } else { " + nested + " } }; f(9000)"
+
+	_, err := New().Eval(context.Background(), src)
+	var limitErr *LimitExceeded
+	if !errors.As(err, &limitErr) || limitErr.Kind != object.LimitEvalDepth {
+		t.Fatalf("expected evaluation depth LimitExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	builtinsMu sync.RWMutex
+	builtins   = map[string]*Builtin{}
+)
+
+// Register makes fn callable from scripts under name, replacing any builtin
+// already registered with that name. Builtins are looked up only after the
+// script's own bindings, so a script can shadow them with let.
+//
+// fn should validate its arguments, for example with CheckArgs, and report
+// problems by returning an *Error.
+func Register(name string, fn BuiltinFunction) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+	builtins[name] = &Builtin{Name: name, Fn: fn}
+}
+
+func LookupBuiltin(name string) (*Builtin, bool) {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+	b, ok := builtins[name]
+	return b, ok
+}
+
+// BuiltinNames returns the names of all registered builtins, sorted.
+func BuiltinNames() []string {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ANY_OBJ can be passed to CheckArgs for a parameter that accepts any type.
+const ANY_OBJ = "ANY"
+
+func NewError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
+
+// CheckArity returns an error if args does not hold exactly want arguments
+// for the builtin called name.
+func CheckArity(name string, args []Object, want int) *Error {
+	if len(args) != want {
+		return NewError("wrong number of arguments to `%s`: got=%d, want=%d", name, len(args), want)
+	}
+	return nil
+}
+
+// CheckArgs returns an error unless there is one argument per type in
+// types and each argument has that type, or the type is ANY_OBJ.
+func CheckArgs(name string, args []Object, types ...ObjectType) *Error {
+	if err := CheckArity(name, args, len(types)); err != nil {
+		return err
+	}
+	for i, t := range types {
+		if t != ANY_OBJ && args[i].Type() != t {
+			return ArgumentError(name, i, args[i], t)
+		}
+	}
+	return nil
+}
+
+// ArgumentError reports that the argument at index i (0-based) of the
+// builtin called name has the wrong type.
+func ArgumentError(name string, i int, got Object, want ...ObjectType) *Error {
+	expected := ""
+	for j, t := range want {
+		switch {
+		case j == 0:
+		case j == len(want)-1:
+			expected += " or "
+		default:
+			expected += ", "
+		}
+		expected += string(t)
+	}
+	return NewError("argument %d to `%s` must be %s, got %s", i+1, name, expected, got.Type())
+}
+
+func init() {
+	Register("len", builtinLen)
+	Register("puts", NewPuts(os.Stdout).Fn)
+	Register("type", builtinType)
+	Register("first", builtinFirst)
+	Register("last", builtinLast)
+	Register("rest", builtinRest)
+	Register("push", builtinPush)
+}
+
+func builtinLen(args ...Object) Object {
+	if err := CheckArity("len", args, 1); err != nil {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *String:
+		return &Integer{Value: int64(len(arg.Value))}
+	case *Array:
+		return &Integer{Value: int64(len(arg.Elements))}
+	case *Hash:
+		return &Integer{Value: int64(len(arg.Pairs))}
+	default:
+		return ArgumentError("len", 0, args[0], STRING_OBJ, ARRAY_OBJ, HASH_OBJ)
+	}
+}
+
+// NewPuts returns a puts builtin that writes to w. The registered puts
+// writes to os.Stdout; a program that wants the output elsewhere binds its
+// own puts, which shadows the registered one.
+func NewPuts(w io.Writer) *Builtin {
+	return &Builtin{Name: "puts", Fn: func(args ...Object) Object {
+		for _, arg := range args {
+			fmt.Fprintln(w, arg.Inspect())
+		}
+		return NULL
+	}}
+}
+
+func builtinType(args ...Object) Object {
+	if err := CheckArgs("type", args, ANY_OBJ); err != nil {
+		return err
+	}
+	return &String{Value: string(args[0].Type())}
+}
+
+func builtinFirst(args ...Object) Object {
+	if err := CheckArgs("first", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	arr := args[0].(*Array)
+	if len(arr.Elements) > 0 {
+		return arr.Elements[0]
+	}
+	return NULL
+}
+
+func builtinLast(args ...Object) Object {
+	if err := CheckArgs("last", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	arr := args[0].(*Array)
+	if length := len(arr.Elements); length > 0 {
+		return arr.Elements[length-1]
+	}
+	return NULL
+}
+
+func builtinRest(args ...Object) Object {
+	if err := CheckArgs("rest", args, ARRAY_OBJ); err != nil {
+		return err
+	}
+	arr := args[0].(*Array)
+	length := len(arr.Elements)
+	if length == 0 {
+		return NULL
+	}
+	newElements := make([]Object, length-1)
+	copy(newElements, arr.Elements[1:length])
+	return &Array{Elements: newElements}
+}
+
+// builtinPush returns a new array; the argument is left unchanged.
+func builtinPush(args ...Object) Object {
+	if err := CheckArgs("push", args, ARRAY_OBJ, ANY_OBJ); err != nil {
+		return err
+	}
+	arr := args[0].(*Array)
+	length := len(arr.Elements)
+	newElements := make([]Object, length+1)
+	copy(newElements, arr.Elements)
+	newElements[length] = args[1]
+	return &Array{Elements: newElements}
+}
@@ -0,0 +1,64 @@
+package object
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestRegisterBuiltin(t *testing.T) {
+	Register("answer", func(args ...Object) Object {
+		return &Integer{Value: 42}
+	})
+
+	b, ok := LookupBuiltin("answer")
+	if !ok {
+		t.Fatalf("builtin answer not found")
+	}
+	if b.Name != "answer" {
+		t.Errorf("builtin has wrong name. got=%q", b.Name)
+	}
+	if got := b.Fn().(*Integer).Value; got != 42 {
+		t.Errorf("builtin returned %d, want 42", got)
+	}
+
+	names := BuiltinNames()
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("BuiltinNames not sorted: %v", names)
+	}
+	for _, want := range []string{"answer", "first", "last", "len", "push", "puts", "rest", "type"} {
+		i := sort.SearchStrings(names, want)
+		if i == len(names) || names[i] != want {
+			t.Errorf("BuiltinNames missing %q: %v", want, names)
+		}
+	}
+}
+
+func TestCheckArgs(t *testing.T) {
+	tests := []struct {
+		args     []Object
+		types    []ObjectType
+		expected string
+	}{
+		{[]Object{&Integer{}}, []ObjectType{INTEGER_OBJ}, ""},
+		{[]Object{&String{}}, []ObjectType{ANY_OBJ}, ""},
+		{[]Object{}, []ObjectType{INTEGER_OBJ}, "wrong number of arguments to `f`: got=0, want=1"},
+		{[]Object{&Integer{}, &String{}}, []ObjectType{INTEGER_OBJ, INTEGER_OBJ}, "argument 2 to `f` must be INTEGER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		err := CheckArgs("f", tt.args, tt.types...)
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err.Message)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, err.Message)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package object
+
+import "sort"
+
+// Environment maps identifiers to values. Function calls get an enclosed
+// environment whose lookups fall back to the scope the function was defined in.
+//
+// An environment may carry a Meter that limits the evaluation running in it.
+// Enclosed environments start out with their outer environment's meter.
+type Environment struct {
+	store map[string]Object
+	outer *Environment
+	meter *Meter
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{store: make(map[string]Object)}
+}
+
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	env.meter = outer.meter
+	return env
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+func (e *Environment) Meter() *Meter { return e.meter }
+
+func (e *Environment) SetMeter(m *Meter) { e.meter = m }
+
+// Names returns the names bound directly in e, not in its outer
+// environments, sorted.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,55 @@
+package object
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnclosedEnvironment(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+	outer.Set("b", &Integer{Value: 2})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("b", &Integer{Value: 20})
+
+	tests := []struct {
+		env      *Environment
+		name     string
+		expected int64
+	}{
+		{inner, "a", 1},
+		{inner, "b", 20},
+		{outer, "b", 2},
+	}
+	for _, tt := range tests {
+		obj, ok := tt.env.Get(tt.name)
+		if !ok {
+			t.Errorf("%s not found", tt.name)
+			continue
+		}
+		if obj.(*Integer).Value != tt.expected {
+			t.Errorf("%s has wrong value. want=%d, got=%d", tt.name, tt.expected, obj.(*Integer).Value)
+		}
+	}
+
+	if _, ok := outer.Get("c"); ok {
+		t.Errorf("outer environment sees bindings it never made")
+	}
+	inner.Set("c", &Integer{Value: 3})
+	if _, ok := outer.Get("c"); ok {
+		t.Errorf("binding in enclosed environment leaked to its outer environment")
+	}
+}
+
+func TestEnvironmentNames(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("z", NULL)
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("b", NULL)
+	inner.Set("a", NULL)
+
+	if got := strings.Join(inner.Names(), ","); got != "a,b" {
+		t.Errorf("wrong names. got=%q", got)
+	}
+}
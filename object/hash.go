@@ -0,0 +1,66 @@
+package object
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// HashKey identifies a hashable value. Keys are equal exactly when the values
+// are, so HashKey can be used as a Go map key.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+	// Text is the value of a string. Keeping the string itself rather than a
+	// hash of it means that no two strings can collide.
+	Text string
+}
+
+// Hashable is implemented by the objects that can be used as hash keys.
+type Hashable interface {
+	HashKey() HashKey
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (s *String) HashKey() HashKey {
+	return HashKey{Type: s.Type(), Text: s.Value}
+}
+
+// HashPair keeps the original key next to the value, since a HashKey cannot
+// be turned back into the object it came from.
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+
+// Inspect lists the pairs sorted by key, so that equal hashes print the
+// same way.
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+	sort.Strings(pairs)
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
@@ -0,0 +1,59 @@
+package object
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStringHashKey(t *testing.T) {
+	hello1 := &String{Value: "Hello World"}
+	hello2 := &String{Value: "Hello World"}
+	diff1 := &String{Value: "My name is johnny"}
+	diff2 := &String{Value: "My name is johnny"}
+
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+	if diff1.HashKey() != diff2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+	if hello1.HashKey() == diff1.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+}
+
+func TestStringHashKeysDoNotCollide(t *testing.T) {
+	// Keys are compared, not hashed down, so every distinct string of a
+	// large set has its own key.
+	seen := map[HashKey]string{}
+	for i := 0; i < 100000; i++ {
+		s := fmt.Sprintf("key%d", i)
+		key := (&String{Value: s}).HashKey()
+		if other, ok := seen[key]; ok {
+			t.Fatalf("%q and %q have the same hash key", s, other)
+		}
+		seen[key] = s
+	}
+}
+
+func TestHashKeysOfDifferentTypes(t *testing.T) {
+	one := &Integer{Value: 1}
+	yes := &Boolean{Value: true}
+
+	if one.HashKey() == yes.HashKey() {
+		t.Errorf("1 and true have the same hash key")
+	}
+	if one.HashKey() != (&Integer{Value: 1}).HashKey() {
+		t.Errorf("integers with same value have different hash keys")
+	}
+}
+
+func TestHashInspect(t *testing.T) {
+	h := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, k := range []*String{{Value: "b"}, {Value: "a"}} {
+		h.Pairs[k.HashKey()] = HashPair{Key: k, Value: &Integer{Value: 1}}
+	}
+	if h.Inspect() != "{a: 1, b: 1}" {
+		t.Errorf("wrong Inspect output. got=%q", h.Inspect())
+	}
+}
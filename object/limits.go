@@ -0,0 +1,205 @@
+package object
+
+import (
+	"context"
+	"fmt"
+)
+
+// Limits bounds the work done by one evaluation. A zero field means no limit.
+type Limits struct {
+	// MaxSteps is the number of AST nodes the evaluator may visit, or the
+	// number of instructions the VM may execute.
+	MaxSteps int
+	// MaxCallDepth is the number of function calls that may be active at once.
+	MaxCallDepth int
+	// MaxMemory is the number of bytes, as estimated by SizeOf, that may be
+	// in use at once.
+	MaxMemory int
+}
+
+// DefaultMaxCallDepth is the call depth that embedders and the command line
+// allow unless told otherwise.
+const DefaultMaxCallDepth = 10000
+
+// MaxEvalDepth is how deeply a metered evaluation may nest, counting every
+// node being evaluated, whatever the other limits say. Calls and nested
+// expressions both add to it, so it is what keeps a script from exhausting
+// the Go stack, which cannot be recovered from. Each level takes about a
+// kilobyte of stack.
+const MaxEvalDepth = 100000
+
+// LimitKind says which limit a LimitExceeded error is about.
+type LimitKind int
+
+const (
+	LimitSteps LimitKind = iota
+	LimitCallDepth
+	LimitMemory
+	LimitContext
+	LimitEvalDepth
+)
+
+func (k LimitKind) String() string {
+	switch k {
+	case LimitSteps:
+		return "step limit"
+	case LimitCallDepth:
+		return "call depth limit"
+	case LimitMemory:
+		return "memory limit"
+	case LimitContext:
+		return "context"
+	case LimitEvalDepth:
+		return "evaluation depth limit"
+	default:
+		return fmt.Sprintf("LimitKind(%d)", int(k))
+	}
+}
+
+// LimitExceeded is the error returned when an evaluation is stopped by a
+// Meter. For LimitContext, Cause holds the context's error.
+type LimitExceeded struct {
+	Kind  LimitKind
+	Limit int
+	Cause error
+}
+
+func (e *LimitExceeded) Error() string {
+	switch e.Kind {
+	case LimitContext:
+		return fmt.Sprintf("evaluation stopped: %s", e.Cause)
+	case LimitMemory:
+		return fmt.Sprintf("%s exceeded (%d bytes)", e.Kind, e.Limit)
+	}
+	return fmt.Sprintf("%s exceeded (%d)", e.Kind, e.Limit)
+}
+
+func (e *LimitExceeded) Unwrap() error { return e.Cause }
+
+// contextCheckInterval is how many steps pass between checks of the context,
+// which is much slower to poll than a counter.
+const contextCheckInterval = 1024
+
+// Meter counts the work done by an evaluation and stops it once a limit is
+// exceeded or its context is done. The methods of a nil *Meter do nothing,
+// so unmetered evaluation pays only for the nil check.
+type Meter struct {
+	ctx    context.Context
+	limits Limits
+	steps  int
+	depth  int
+	// nesting is the number of nodes being evaluated, one inside the other.
+	nesting int
+	memory  int
+	peak    int
+}
+
+func NewMeter(ctx context.Context, limits Limits) *Meter {
+	return &Meter{ctx: ctx, limits: limits}
+}
+
+// Step records one unit of work.
+func (m *Meter) Step() error {
+	if m == nil {
+		return nil
+	}
+	m.steps++
+	if m.limits.MaxSteps > 0 && m.steps > m.limits.MaxSteps {
+		return &LimitExceeded{Kind: LimitSteps, Limit: m.limits.MaxSteps}
+	}
+	if m.ctx != nil && m.steps%contextCheckInterval == 0 {
+		return m.checkContext()
+	}
+	return nil
+}
+
+func (m *Meter) checkContext() error {
+	if err := m.ctx.Err(); err != nil {
+		return &LimitExceeded{Kind: LimitContext, Cause: err}
+	}
+	return nil
+}
+
+// Enter records the start of a function call. Every successful Enter must
+// be paired with a Leave.
+func (m *Meter) Enter() error {
+	if m == nil {
+		return nil
+	}
+	if m.limits.MaxCallDepth > 0 && m.depth >= m.limits.MaxCallDepth {
+		return &LimitExceeded{Kind: LimitCallDepth, Limit: m.limits.MaxCallDepth}
+	}
+	m.depth++
+	return nil
+}
+
+func (m *Meter) Leave() {
+	if m != nil {
+		m.depth--
+	}
+}
+
+// Descend records that evaluation is entering a node. Every successful
+// Descend must be paired with an Ascend.
+func (m *Meter) Descend() error {
+	if m == nil {
+		return nil
+	}
+	if m.nesting >= MaxEvalDepth {
+		return &LimitExceeded{Kind: LimitEvalDepth, Limit: MaxEvalDepth}
+	}
+	m.nesting++
+	return nil
+}
+
+func (m *Meter) Ascend() {
+	if m != nil {
+		m.nesting--
+	}
+}
+
+// Steps returns the number of steps recorded so far.
+func (m *Meter) Steps() int {
+	if m == nil {
+		return 0
+	}
+	return m.steps
+}
+
+// Alloc records that n bytes were allocated.
+func (m *Meter) Alloc(n int) error {
+	if m == nil {
+		return nil
+	}
+	m.memory += n
+	if m.memory > m.peak {
+		m.peak = m.memory
+	}
+	if m.limits.MaxMemory > 0 && m.memory > m.limits.MaxMemory {
+		return &LimitExceeded{Kind: LimitMemory, Limit: m.limits.MaxMemory}
+	}
+	return nil
+}
+
+// Free records that n bytes are no longer reachable.
+func (m *Meter) Free(n int) {
+	if m != nil {
+		m.memory -= n
+	}
+}
+
+// Memory returns the number of bytes currently in use.
+func (m *Meter) Memory() int {
+	if m == nil {
+		return 0
+	}
+	return m.memory
+}
+
+// PeakMemory returns the largest number of bytes that were in use at once.
+func (m *Meter) PeakMemory() int {
+	if m == nil {
+		return 0
+	}
+	return m.peak
+}
@@ -0,0 +1,107 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMeterSteps(t *testing.T) {
+	m := NewMeter(context.Background(), Limits{MaxSteps: 3})
+	for i := 0; i < 3; i++ {
+		if err := m.Step(); err != nil {
+			t.Fatalf("step %d: unexpected error %s", i, err)
+		}
+	}
+	err := m.Step()
+	var limitErr *LimitExceeded
+	if !errors.As(err, &limitErr) || limitErr.Kind != LimitSteps {
+		t.Fatalf("expected step LimitExceeded, got %v", err)
+	}
+	if want := "step limit exceeded (3)"; err.Error() != want {
+		t.Errorf("wrong message. want=%q, got=%q", want, err.Error())
+	}
+}
+
+func TestMeterCallDepth(t *testing.T) {
+	m := NewMeter(context.Background(), Limits{MaxCallDepth: 2})
+	if err := m.Enter(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Enter(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Enter(); err == nil || err.(*LimitExceeded).Kind != LimitCallDepth {
+		t.Fatalf("expected call depth LimitExceeded, got %v", err)
+	}
+	m.Leave()
+	if err := m.Enter(); err != nil {
+		t.Errorf("Enter after Leave: %s", err)
+	}
+}
+
+func TestMeterEvalDepth(t *testing.T) {
+	m := NewMeter(context.Background(), Limits{})
+	for i := 0; i < MaxEvalDepth; i++ {
+		if err := m.Descend(); err != nil {
+			t.Fatalf("Descend %d: %s", i, err)
+		}
+	}
+	if err := m.Descend(); err == nil || err.(*LimitExceeded).Kind != LimitEvalDepth {
+		t.Fatalf("expected evaluation depth LimitExceeded, got %v", err)
+	}
+	m.Ascend()
+	if err := m.Descend(); err != nil {
+		t.Errorf("Descend after Ascend: %s", err)
+	}
+}
+
+func TestMeterContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := NewMeter(ctx, Limits{})
+	cancel()
+
+	var err error
+	for i := 0; i < contextCheckInterval && err == nil; i++ {
+		err = m.Step()
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if err.(*LimitExceeded).Kind != LimitContext {
+		t.Errorf("wrong kind %s", err.(*LimitExceeded).Kind)
+	}
+}
+
+func TestNilMeter(t *testing.T) {
+	var m *Meter
+	if err := m.Step(); err != nil {
+		t.Error(err)
+	}
+	if err := m.Enter(); err != nil {
+		t.Error(err)
+	}
+	m.Leave()
+}
+
+func TestMeterMemory(t *testing.T) {
+	m := NewMeter(context.Background(), Limits{MaxMemory: 100})
+	if err := m.Alloc(60); err != nil {
+		t.Fatal(err)
+	}
+	m.Free(50)
+	if err := m.Alloc(80); err != nil {
+		t.Fatal(err)
+	}
+	if m.Memory() != 90 || m.PeakMemory() != 90 {
+		t.Errorf("wrong usage. memory=%d, peak=%d", m.Memory(), m.PeakMemory())
+	}
+
+	err := m.Alloc(20)
+	if err == nil || err.(*LimitExceeded).Kind != LimitMemory {
+		t.Fatalf("expected memory LimitExceeded, got %v", err)
+	}
+	if want := "memory limit exceeded (100 bytes)"; err.Error() != want {
+		t.Errorf("wrong message. want=%q, got=%q", want, err.Error())
+	}
+}
@@ -0,0 +1,134 @@
+package object
+
+import "context"
+
+// The sizes below are rough estimates of what values cost on a 64-bit
+// platform. They only need to be good enough to tell a script that builds a
+// gigantic string from one that does not. Integers, booleans and null are
+// small enough not to be counted.
+const (
+	objectSize   = 16 // an object header
+	elementSize  = 16 // an interface value in an array
+	hashPairSize = 48 // a HashKey and a HashPair in a map
+	functionSize = 48
+
+	// EnvironmentSize is the cost of an empty Environment.
+	EnvironmentSize = 48
+	// BindingSize is the cost of one name in an Environment.
+	BindingSize = 32
+)
+
+// SizeOf estimates the memory held by obj itself, not counting the values
+// it refers to.
+func SizeOf(obj Object) int {
+	switch obj := obj.(type) {
+	case *String:
+		return objectSize + len(obj.Value)
+	case *Array:
+		return objectSize + elementSize*len(obj.Elements)
+	case *Hash:
+		return objectSize + hashPairSize*len(obj.Pairs)
+	case *Function:
+		return functionSize
+	default:
+		return 0
+	}
+}
+
+// RetainedSize estimates the memory kept alive by obj, including the values
+// it refers to. retainsEnv reports whether obj holds a function, which keeps
+// the whole environment it was defined in alive.
+func RetainedSize(obj Object) (size int, retainsEnv bool) {
+	switch obj := obj.(type) {
+	case *Array, *Hash:
+	case *Function:
+		return SizeOf(obj), true
+	default:
+		return SizeOf(obj), false
+	}
+	s := &sizer{seen: map[interface{}]bool{}}
+	size = s.value(obj)
+	return size, s.retainsEnv
+}
+
+// ReachableSize estimates the memory kept alive by env: its bindings, the
+// values they hold, and the environments of any functions among them and
+// the scopes enclosing those. It gives up with a *LimitExceeded once ctx is
+// done.
+func ReachableSize(ctx context.Context, env *Environment) (int, error) {
+	s := &sizer{seen: map[interface{}]bool{}, ctx: ctx, envs: true}
+	size := s.env(env)
+	return size, s.err
+}
+
+// sizer adds up the memory held by values. Each string, array, hash,
+// function and environment is counted once however often it is referred
+// to, which also keeps the walk linear in the number of distinct values.
+type sizer struct {
+	seen map[interface{}]bool
+	// envs says whether the environments of functions are walked too.
+	envs       bool
+	retainsEnv bool
+
+	// ctx, if set, is checked every contextCheckInterval values.
+	ctx     context.Context
+	visited int
+	err     error
+}
+
+func (s *sizer) value(obj Object) int {
+	switch obj.(type) {
+	case *String, *Array, *Hash, *Function:
+	default:
+		return 0
+	}
+	if s.seen[obj] || s.stopped() {
+		return 0
+	}
+	s.seen[obj] = true
+
+	size := SizeOf(obj)
+	switch obj := obj.(type) {
+	case *Function:
+		s.retainsEnv = true
+		if s.envs {
+			size += s.env(obj.Env)
+		}
+	case *Array:
+		for _, el := range obj.Elements {
+			size += s.value(el)
+		}
+	case *Hash:
+		for _, pair := range obj.Pairs {
+			size += s.value(pair.Key) + s.value(pair.Value)
+		}
+	}
+	return size
+}
+
+func (s *sizer) env(env *Environment) int {
+	size := 0
+	for ; env != nil && !s.seen[env] && !s.stopped(); env = env.outer {
+		s.seen[env] = true
+		size += EnvironmentSize + BindingSize*len(env.store)
+		for _, val := range env.store {
+			size += s.value(val)
+		}
+	}
+	return size
+}
+
+// stopped counts a visit and reports whether the walk must end because its
+// context is done.
+func (s *sizer) stopped() bool {
+	if s.err != nil {
+		return true
+	}
+	s.visited++
+	if s.ctx != nil && s.visited%contextCheckInterval == 0 {
+		if err := s.ctx.Err(); err != nil {
+			s.err = &LimitExceeded{Kind: LimitContext, Cause: err}
+		}
+	}
+	return s.err != nil
+}
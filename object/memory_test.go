@@ -0,0 +1,93 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRetainedSize(t *testing.T) {
+	str := &String{Value: "hello"}
+	tests := []struct {
+		obj        Object
+		size       int
+		retainsEnv bool
+	}{
+		{&Integer{Value: 1}, 0, false},
+		{str, SizeOf(str), false},
+		{&Array{Elements: []Object{str, TRUE}}, SizeOf(&Array{Elements: []Object{str, TRUE}}) + SizeOf(str), false},
+		{&Array{Elements: []Object{&Function{}}}, SizeOf(&Array{Elements: []Object{nil}}) + SizeOf(&Function{}), true},
+	}
+
+	for i, tt := range tests {
+		size, retainsEnv := RetainedSize(tt.obj)
+		if size != tt.size || retainsEnv != tt.retainsEnv {
+			t.Errorf("tests[%d]: got (%d, %t), want (%d, %t)", i, size, retainsEnv, tt.size, tt.retainsEnv)
+		}
+	}
+
+	if SizeOf(&String{Value: "ab"}) >= SizeOf(&String{Value: "abcdef"}) {
+		t.Errorf("longer strings should be larger")
+	}
+}
+
+func TestReachableSize(t *testing.T) {
+	global := NewEnvironment()
+	if got, _ := ReachableSize(context.Background(), global); got != EnvironmentSize {
+		t.Errorf("empty environment: got %d, want %d", got, EnvironmentSize)
+	}
+
+	str := &String{Value: "kept"}
+	call := NewEnclosedEnvironment(global)
+	call.Set("s", str)
+	closure := &Function{Env: call}
+	global.Set("f", closure)
+	global.Set("g", closure)
+
+	want := 2*EnvironmentSize + 3*BindingSize + SizeOf(closure) + SizeOf(str)
+	if got, _ := ReachableSize(context.Background(), global); got != want {
+		t.Errorf("got %d, want %d", got, want)
+	}
+}
+
+// nestedPairs returns n levels of arrays that each hold the level below
+// twice, which a walk that does not notice sharing visits 2^n times.
+func nestedPairs(n int) Object {
+	var obj Object = &Integer{Value: 1}
+	for i := 0; i < n; i++ {
+		obj = &Array{Elements: []Object{obj, obj}}
+	}
+	return obj
+}
+
+func TestSharedValuesAreCountedOnce(t *testing.T) {
+	obj := nestedPairs(64)
+	want := 64 * SizeOf(&Array{Elements: []Object{nil, nil}})
+	if got, _ := RetainedSize(obj); got != want {
+		t.Errorf("RetainedSize: got %d, want %d", got, want)
+	}
+
+	env := NewEnvironment()
+	env.Set("a", obj)
+	env.Set("b", obj)
+	if got, _ := ReachableSize(context.Background(), env); got != EnvironmentSize+2*BindingSize+want {
+		t.Errorf("ReachableSize: got %d, want %d", got, EnvironmentSize+2*BindingSize+want)
+	}
+}
+
+func TestReachableSizeStopsWithContext(t *testing.T) {
+	env := NewEnvironment()
+	elements := make([]Object, 4*contextCheckInterval)
+	for i := range elements {
+		elements[i] = &String{Value: "x"}
+	}
+	env.Set("a", &Array{Elements: elements})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ReachableSize(ctx, env)
+	var limitErr *LimitExceeded
+	if !errors.As(err, &limitErr) || limitErr.Kind != LimitContext {
+		t.Errorf("expected a context LimitExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package object
+
+import "math"
+
+// IntPow raises base to the non-negative power exp by repeated squaring,
+// wrapping on overflow like the other integer operators.
+func IntPow(base, exp int64) int64 {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+	return result
+}
+
+// IsNumber reports whether obj is an INTEGER or a FLOAT.
+func IsNumber(obj Object) bool {
+	return obj.Type() == INTEGER_OBJ || obj.Type() == FLOAT_OBJ
+}
+
+// ToFloat returns the value of an INTEGER or FLOAT as a float64.
+func ToFloat(obj Object) float64 {
+	if i, ok := obj.(*Integer); ok {
+		return float64(i.Value)
+	}
+	return obj.(*Float).Value
+}
+
+// FloatInfix applies an infix operator to two floats, returning nil for an
+// operator that floats do not support. Division by zero gives an infinity or
+// NaN, as IEEE 754 has it. Operations that mix integers and floats convert
+// the integer with ToFloat first.
+func FloatInfix(operator string, left, right float64) Object {
+	switch operator {
+	case "+":
+		return &Float{Value: left + right}
+	case "-":
+		return &Float{Value: left - right}
+	case "*":
+		return &Float{Value: left * right}
+	case "/":
+		return &Float{Value: left / right}
+	case "%":
+		return &Float{Value: math.Mod(left, right)}
+	case "**":
+		return &Float{Value: math.Pow(left, right)}
+	case "<":
+		return nativeBool(left < right)
+	case ">":
+		return nativeBool(left > right)
+	case "<=":
+		return nativeBool(left <= right)
+	case ">=":
+		return nativeBool(left >= right)
+	case "==":
+		return nativeBool(left == right)
+	case "!=":
+		return nativeBool(left != right)
+	default:
+		return nil
+	}
+}
+
+func nativeBool(b bool) *Boolean {
+	if b {
+		return TRUE
+	}
+	return FALSE
+}
@@ -0,0 +1,46 @@
+package object
+
+import "testing"
+
+func TestIntPow(t *testing.T) {
+	tests := []struct {
+		base, exp, expected int64
+	}{
+		{2, 0, 1},
+		{2, 10, 1024},
+		{-3, 3, -27},
+		{0, 0, 1},
+		{2, 64, 0},
+	}
+
+	for _, tt := range tests {
+		if got := IntPow(tt.base, tt.exp); got != tt.expected {
+			t.Errorf("IntPow(%d, %d): expected %d, got %d", tt.base, tt.exp, tt.expected, got)
+		}
+	}
+}
+
+func TestFloatInfix(t *testing.T) {
+	tests := []struct {
+		operator    string
+		left, right float64
+		expected    string
+	}{
+		{"+", 1.5, 1, "2.5"},
+		{"/", 1, 0, "+Inf"},
+		{"%", 7.5, 2, "1.5"},
+		{"**", 2, 0.5, "1.4142135623730951"},
+		{">=", 2, 2, "true"},
+		{"!=", 1, 1, "false"},
+	}
+
+	for _, tt := range tests {
+		result := FloatInfix(tt.operator, tt.left, tt.right)
+		if result == nil || result.Inspect() != tt.expected {
+			t.Errorf("%g %s %g: expected %s, got %v", tt.left, tt.operator, tt.right, tt.expected, result)
+		}
+	}
+	if result := FloatInfix("&", 1, 1); result != nil {
+		t.Errorf("expected no result for &, got %s", result.Inspect())
+	}
+}
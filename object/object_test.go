@@ -0,0 +1,30 @@
+package object
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3, "3.0"},
+		{-0.5, "-0.5"},
+		{0, "0.0"},
+		{1e20, "100000000000000000000.0"},
+		{1e21, "1e+21"},
+		{0.0001, "0.0001"},
+		{1.5e-5, "1.5e-05"},
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+		{math.NaN(), "NaN"},
+	}
+
+	for _, tt := range tests {
+		if got := (&Float{Value: tt.value}).Inspect(); got != tt.expected {
+			t.Errorf("Inspect(%g): expected %q, got %q", tt.value, tt.expected, got)
+		}
+	}
+}
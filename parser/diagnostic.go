@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/dawkaka/go-interpreter/token"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Code identifies the kind of a diagnostic so tools can react to it without
+// matching on the message text.
+type Code string
+
+const (
+	CodeUnexpectedToken Code = "P0001"
+	CodeNoPrefixParseFn Code = "P0002"
+	CodeInvalidInteger  Code = "P0003"
+	CodeUnexpectedEOF   Code = "P0004"
+	CodeIllegalToken    Code = "P0005"
+	CodeNestingTooDeep  Code = "P0006"
+	CodeInvalidFloat    Code = "P0007"
+)
+
+// Diagnostic is a problem found while parsing, located by the span of source
+// it refers to.
+type Diagnostic struct {
+	Severity Severity
+	Code     Code
+	Span     token.Span
+	Message  string
+	// Hint optionally suggests how to fix the problem.
+	Hint string
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s", d.Span.Start, d.Message)
+}
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/dawkaka/go-interpreter/ast"
@@ -17,46 +19,93 @@ type (
 const (
 	_ int = iota
 	LOWEST
+	LOGICAL_OR
+	LOGICAL_AND
+	BIT_OR
+	BIT_XOR
+	BIT_AND
 	EQUALS
 	LESSGREATER
+	SHIFT
 	SUM
 	PRODUCT
 	PREFIX
+	POWER
 	CALL
+	INDEX
 )
 
 var precedence = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
+	token.EQ:          EQUALS,
+	token.NOT_EQ:      EQUALS,
+	token.OR:          LOGICAL_OR,
+	token.AND:         LOGICAL_AND,
+	token.PIPE:        BIT_OR,
+	token.CARET:       BIT_XOR,
+	token.AMPERSAND:   BIT_AND,
+	token.SHIFT_LEFT:  SHIFT,
+	token.SHIFT_RIGHT: SHIFT,
+	token.LT:          LESSGREATER,
+	token.GT:          LESSGREATER,
+	token.LT_EQ:       LESSGREATER,
+	token.GT_EQ:       LESSGREATER,
+	token.PLUS:        SUM,
+	token.MINUS:       SUM,
+	token.SLASH:       PRODUCT,
+	token.ASTERISK:    PRODUCT,
+	token.PERCENT:     PRODUCT,
+	token.POWER:       POWER,
+	token.LPAREN:      CALL,
+	token.LBRACKET:    INDEX,
 }
 
+// rightAssociative holds the operators that group from the right, so that
+// "a ** b ** c" is "a ** (b ** c)". POWER binds tighter than PREFIX, making
+// "-a ** b" mean "-(a ** b)".
+var rightAssociative = map[token.TokenType]bool{
+	token.POWER: true,
+}
+
+// MaxNestingDepth is how deeply expressions may nest before the parser gives
+// up, so that inputs such as "((((((..." cannot exhaust the stack of the
+// parser or of the evaluator that walks the result.
+const MaxNestingDepth = 512
+
 type Parser struct {
-	l              *lexer.Lexer
-	currToken      token.Token
-	peekToken      token.Token
-	errors         []string
+	l         *lexer.Lexer
+	currToken token.Token
+	peekToken token.Token
+	errors    []*Diagnostic
+	depth     int
+	// tooDeep is set once MaxNestingDepth is exceeded. The rest of the input
+	// is skipped and any further errors, which would only be consequences of
+	// the abandoned nesting, are dropped.
+	tooDeep        bool
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{l: l}
+	l.Error = p.lexerError
 	p.NextToken()
 	p.NextToken()
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefixFn(token.IDENT, p.parseIdentifier)
 	p.registerPrefixFn(token.INT, p.parseIntegerLiteral)
+	p.registerPrefixFn(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefixFn(token.STRING, p.parseStringLiteral)
+	p.registerPrefixFn(token.ILLEGAL, p.parseIllegal)
 	p.registerPrefixFn(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefixFn(token.BANG, p.parsePrefixExpression)
+	p.registerPrefixFn(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefixFn(token.TRUE, p.parseBoolean)
 	p.registerPrefixFn(token.FALSE, p.parseBoolean)
 	p.registerPrefixFn(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefixFn(token.IF, p.parseIfExpression)
+	p.registerPrefixFn(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefixFn(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefixFn(token.LBRACE, p.parseHashLiteral)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfixFn(token.PLUS, p.parseInfixExpression)
@@ -67,6 +116,19 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfixFn(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfixFn(token.LT, p.parseInfixExpression)
 	p.registerInfixFn(token.GT, p.parseInfixExpression)
+	p.registerInfixFn(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfixFn(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfixFn(token.PERCENT, p.parseInfixExpression)
+	p.registerInfixFn(token.AND, p.parseInfixExpression)
+	p.registerInfixFn(token.OR, p.parseInfixExpression)
+	p.registerInfixFn(token.AMPERSAND, p.parseInfixExpression)
+	p.registerInfixFn(token.PIPE, p.parseInfixExpression)
+	p.registerInfixFn(token.CARET, p.parseInfixExpression)
+	p.registerInfixFn(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfixFn(token.SHIFT_RIGHT, p.parseInfixExpression)
+	p.registerInfixFn(token.POWER, p.parseInfixExpression)
+	p.registerInfixFn(token.LPAREN, p.parseCallExpression)
+	p.registerInfixFn(token.LBRACKET, p.parseIndexExpression)
 	return p
 }
 
@@ -82,6 +144,8 @@ func (p *Parser) ParseProgram() *ast.Program {
 		stm := p.ParseStatement()
 		if stm != nil {
 			program.Statements = append(program.Statements, stm)
+		} else {
+			p.synchronize()
 		}
 		p.NextToken()
 	}
@@ -99,13 +163,85 @@ func (p *Parser) ParseStatement() ast.Statement {
 	}
 }
 
-func (p *Parser) Errors() []string {
+// Errors returns the diagnostics reported while parsing, in source order
+// of discovery.
+func (p *Parser) Errors() []*Diagnostic {
 	return p.errors
 }
 
+// Incomplete reports whether parsing failed only because the input ended
+// too early, as in "let f = fn(x) {". Adding more input may fix such a
+// program, unlike one with any other error.
+func (p *Parser) Incomplete() bool {
+	if len(p.errors) == 0 {
+		return false
+	}
+	for _, err := range p.errors {
+		if err.Code != CodeUnexpectedEOF {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Parser) addError(code Code, span token.Span, hint string, format string, a ...interface{}) {
+	if p.tooDeep {
+		return
+	}
+	p.errors = append(p.errors, &Diagnostic{
+		Severity: SeverityError,
+		Code:     code,
+		Span:     span,
+		Message:  fmt.Sprintf(format, a...),
+		Hint:     hint,
+	})
+}
+
+// nestingTooDeep reports that MaxNestingDepth was exceeded at span and skips
+// the rest of the input.
+func (p *Parser) nestingTooDeep(span token.Span) {
+	p.addError(CodeNestingTooDeep, span, "", "expression nested too deeply (limit %d)", MaxNestingDepth)
+	p.tooDeep = true
+	for !p.peekTokenIs(token.EOF) {
+		p.NextToken()
+	}
+}
+
 func (p *Parser) peekTokenError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	if p.peekTokenIs(token.EOF) {
+		p.addError(CodeUnexpectedEOF, p.peekToken.Span(), expectHint(t), "unexpected end of input, expected %s", t)
+		return
+	}
+	p.addError(CodeUnexpectedToken, p.peekToken.Span(), expectHint(t),
+		"expected next token to be %s, got %s instead", t, p.peekToken.Type)
+}
+
+func expectHint(t token.TokenType) string {
+	switch t {
+	case token.IDENT:
+		return "expected a name here"
+	case token.ASSIGN:
+		return "let bindings are written as `let name = value;`"
+	case token.RPAREN:
+		return "check for a missing `)` or `,`"
+	case token.RBRACKET:
+		return "check for a missing `]` or `,`"
+	case token.LBRACE:
+		return "bodies of if expressions and functions are wrapped in `{ }`"
+	}
+	return ""
+}
+
+// synchronize skips the rest of a statement that failed to parse, so that
+// parsing can pick up again at the next statement. It stops on a `;` or
+// `}`, or before a token that starts a new statement.
+func (p *Parser) synchronize() {
+	for !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		if p.peekTokenIs(token.LET) || p.peekTokenIs(token.RETURN) || p.peekTokenIs(token.EOF) {
+			return
+		}
+		p.NextToken()
+	}
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -123,6 +259,7 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 		p.NextToken()
 		return true
 	}
+	p.peekTokenError(t)
 	return false
 }
 
@@ -148,18 +285,51 @@ func (p *Parser) parseBoolean() ast.Expression {
 }
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	v, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.addError(CodeInvalidInteger, p.currToken.Span(), "", "integer %s does not fit in 64 bits; the largest integer is %d", p.currToken.Literal, int64(math.MaxInt64))
+		return nil
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.currToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(CodeInvalidInteger, p.currToken.Span(), "", "could not parse %q as integer", p.currToken.Literal)
 		return nil
 	}
 	return &ast.IntegerLiteral{Token: p.currToken, Value: v}
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	v, err := strconv.ParseFloat(p.currToken.Literal, 64)
+	if err != nil {
+		p.addError(CodeInvalidFloat, p.currToken.Span(), "", "could not parse %q as float", p.currToken.Literal)
+		return nil
+	}
+	return &ast.FloatLiteral{Token: p.currToken, Value: v}
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+}
+
+// parseIllegal skips over an ILLEGAL token. The lexer has already reported
+// why the token is malformed through lexerError.
+func (p *Parser) parseIllegal() ast.Expression {
+	return nil
+}
+
+func (p *Parser) lexerError(span token.Span, msg string) {
+	code := CodeIllegalToken
+	if msg == lexer.UnterminatedString {
+		code = CodeUnexpectedEOF
+	}
+	p.addError(code, span, "", "%s", msg)
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{Token: p.currToken, Operator: p.currToken.Literal}
 	p.NextToken()
 	expression.Right = p.parseExpression(PREFIX)
+	if expression.Right == nil {
+		return nil
+	}
 	return expression
 }
 
@@ -170,8 +340,16 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Operator: p.currToken.Literal,
 	}
 	precedence := p.currTokenPrecedence()
+	if rightAssociative[p.currToken.Type] {
+		// Parsing the right side one level lower lets it take in another
+		// operator of the same precedence.
+		precedence--
+	}
 	p.NextToken()
 	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
 	return expression
 }
 
@@ -193,7 +371,12 @@ func (p *Parser) ParseLetStatement() ast.Statement {
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
-	for !p.curTokenIs(token.SEMICOLON) {
+	p.NextToken()
+	ltStm.Value = p.parseExpression(LOWEST)
+	if ltStm.Value == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.NextToken()
 	}
 	return ltStm
@@ -202,7 +385,11 @@ func (p *Parser) ParseLetStatement() ast.Statement {
 func (p *Parser) ParseReturnStatement() ast.Statement {
 	rs := &ast.ReturnStatement{Token: p.currToken}
 	p.NextToken()
-	for !p.curTokenIs(token.SEMICOLON) {
+	rs.ReturnValue = p.parseExpression(LOWEST)
+	if rs.ReturnValue == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.NextToken()
 	}
 	return rs
@@ -211,6 +398,9 @@ func (p *Parser) ParseReturnStatement() ast.Statement {
 func (p *Parser) ParseExpressionStatement() ast.Statement {
 	stm := &ast.ExpressionStatement{Tokken: p.currToken}
 	stm.Expression = p.parseExpression(LOWEST)
+	if stm.Expression == nil {
+		return nil
+	}
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.NextToken()
 	}
@@ -219,23 +409,51 @@ func (p *Parser) ParseExpressionStatement() ast.Statement {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	hint := fmt.Sprintf("%q cannot start an expression", p.currToken.Literal)
+	p.addError(CodeNoPrefixParseFn, p.currToken.Span(), hint, "no prefix parse function for %s found", t)
+}
+
+func (p *Parser) unexpectedEOFError(expected string) {
+	p.addError(CodeUnexpectedEOF, p.currToken.Span(), "", "unexpected end of input, expected %s", expected)
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	if p.curTokenIs(token.EOF) {
+		p.unexpectedEOFError("an expression")
+		return nil
+	}
 	prefix := p.prefixParseFns[p.currToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.currToken.Type)
 		return nil
 	}
+	if p.depth == MaxNestingDepth {
+		p.nestingTooDeep(p.currToken.Span())
+		return nil
+	}
+	// An operator applied to the result of another nests it one level
+	// deeper, so chained operators count towards the depth like
+	// parentheses: "1 + 1 + ... + 1" is as deep as it is long.
+	levels := 1
+	p.depth++
+	defer func() { p.depth -= levels }()
 
 	leftExp := prefix()
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekTokenPrecedence() {
+	chained := false
+	for leftExp != nil && !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekTokenPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
 		}
+		if chained {
+			if p.depth == MaxNestingDepth {
+				p.nestingTooDeep(p.peekToken.Span())
+				return nil
+			}
+			levels++
+			p.depth++
+		}
+		chained = true
 		p.NextToken()
 		leftExp = infix(leftExp)
 	}
@@ -246,8 +464,239 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.NextToken()
 
 	exp := p.parseExpression(LOWEST)
+	if exp == nil || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return exp
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+	expression := &ast.IfExpression{Token: p.currToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.NextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+	if expression.Condition == nil {
+		return nil
+	}
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	expression.Consequence = p.parseBlockStatement()
+	if expression.Consequence == nil {
+		return nil
+	}
+
+	if !p.peekTokenIs(token.ELSE) {
+		return expression
+	}
+	p.NextToken()
+
+	if p.peekTokenIs(token.IF) {
+		p.NextToken()
+		elseIf := &ast.BlockStatement{Token: p.currToken}
+		nested := p.parseIfExpression()
+		if nested == nil {
+			return nil
+		}
+		elseIf.Statements = []ast.Statement{
+			&ast.ExpressionStatement{Tokken: elseIf.Token, Expression: nested},
+		}
+		expression.Alternative = elseIf
+		return expression
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	expression.Alternative = p.parseBlockStatement()
+	if expression.Alternative == nil {
+		return nil
+	}
+	return expression
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.currToken}
+	block.Statements = []ast.Statement{}
+	p.NextToken()
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			p.unexpectedEOFError(token.RBRACE)
+			return nil
+		}
+		stm := p.ParseStatement()
+		if stm != nil {
+			block.Statements = append(block.Statements, stm)
+		} else {
+			p.synchronize()
+			if p.curTokenIs(token.RBRACE) || p.curTokenIs(token.EOF) {
+				continue
+			}
+		}
+		p.NextToken()
+	}
+	block.Rbrace = p.currToken
+	return block
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	fn := &ast.FunctionLiteral{Token: p.currToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	fn.Parameters = p.parseFunctionParameters()
+	if fn.Parameters == nil {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	fn.Body = p.parseBlockStatement()
+	if fn.Body == nil {
+		return nil
+	}
+	return fn
+}
+
+func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	identifiers := []*ast.Identifier{}
+	if p.peekTokenIs(token.RPAREN) {
+		p.NextToken()
+		return identifiers
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	identifiers = append(identifiers, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+	for p.peekTokenIs(token.COMMA) {
+		p.NextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		identifiers = append(identifiers, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return identifiers
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.currToken, Function: function}
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return nil
+	}
+	exp.Rparen = p.currToken
 	return exp
 }
+
+// parseExpressionList parses comma-separated expressions up to and including
+// the end token. It returns nil if the list is malformed.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+	if p.peekTokenIs(end) {
+		p.NextToken()
+		return list
+	}
+
+	p.NextToken()
+	exp := p.parseExpression(LOWEST)
+	if exp == nil {
+		return nil
+	}
+	list = append(list, exp)
+	for p.peekTokenIs(token.COMMA) {
+		p.NextToken()
+		p.NextToken()
+		exp := p.parseExpression(LOWEST)
+		if exp == nil {
+			return nil
+		}
+		list = append(list, exp)
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+	return list
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.currToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+	array.Rbracket = p.currToken
+	return array
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.currToken, Left: left}
+	p.NextToken()
+	exp.Index = p.parseExpression(LOWEST)
+	if exp.Index == nil {
+		return nil
+	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	exp.Rbracket = p.currToken
+	return exp
+}
+
+// blockInExpressionHint explains why a `{` that was meant to open a block
+// is parsed as a hash literal.
+const blockInExpressionHint = "a `{` in expression position starts a hash literal; blocks are only allowed after if, else and fn"
+
+// parseHashLiteral parses `{key: value, ...}`. Blocks only ever follow if,
+// else and fn, which parse them directly, so a `{` that reaches the prefix
+// table is always a hash literal.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.currToken, Pairs: []ast.HashPair{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if p.peekTokenIs(token.LET) || p.peekTokenIs(token.RETURN) {
+			p.addError(CodeUnexpectedToken, p.peekToken.Span(), blockInExpressionHint,
+				"expected a hash key, got %s instead", p.peekToken.Type)
+			return nil
+		}
+		p.NextToken()
+		key := p.parseExpression(LOWEST)
+		if key == nil {
+			return nil
+		}
+		if !p.peekTokenIs(token.COLON) {
+			hint := ""
+			if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) {
+				hint = blockInExpressionHint
+			}
+			p.addError(CodeUnexpectedToken, p.peekToken.Span(), hint,
+				"expected next token to be %s, got %s instead", token.COLON, p.peekToken.Type)
+			return nil
+		}
+		p.NextToken()
+		p.NextToken()
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.NextToken()
+	hash.Rbrace = p.currToken
+	return hash
+}
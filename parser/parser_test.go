@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/dawkaka/go-interpreter/ast"
@@ -10,28 +11,58 @@ import (
 )
 
 func TestLetStatement(t *testing.T) {
-	input := `
-let x = 5;
-let y = 10;
-let foobar = 838383;`
-
-	l := lexer.New(input)
-	p := New(l)
-
-	program := p.ParseProgram()
-	checkParsedErrors(t, p)
-	if len(program.Statements) != 3 {
-		t.Fatalf("Expected length to be 3 but got %d", len(program.Statements))
+	tests := []struct {
+		input              string
+		expectedIdentifier string
+		expectedValue      interface{}
+	}{
+		{"let x = 5;", "x", 5},
+		{"let y = 10;", "y", 10},
+		{"let foobar = y;", "foobar", "y"},
+		{"let z = 838383", "z", 838383},
 	}
 
-	tests := []struct{ expectedIdentifier string }{{"x"}, {"y"}, {"foobar"}}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParsedErrors(t, p)
+		if len(program.Statements) != 1 {
+			t.Fatalf("Expected length to be 1 but got %d", len(program.Statements))
+		}
 
-	for i, tt := range tests {
-		st := program.Statements[i]
+		st := program.Statements[0]
 		if !testLetStatement(t, st, tt.expectedIdentifier) {
 			return
 		}
+		val := st.(*ast.LetStatement).Value
+		if !testLiteralExpression(t, val, tt.expectedValue) {
+			return
+		}
+	}
+}
 
+func TestIncompleteStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let x =", "1:8: unexpected end of input, expected an expression"},
+		{"return", "1:7: unexpected end of input, expected an expression"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(program.Statements) != 0 {
+			t.Errorf("input %q: expected no statements, got %d", tt.input, len(program.Statements))
+		}
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("input %q: expected 1 error, got %d: %v", tt.input, len(errors), errors)
+		}
+		if errors[0].Error() != tt.expectedError {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
 	}
 }
 
@@ -70,28 +101,34 @@ func testLetStatement(t *testing.T, st ast.Statement, name string) bool {
 }
 
 func TestReturnStatement(t *testing.T) {
-	input := `
-return 5;
-return 10;
-return 993322;
-`
-	l := lexer.New(input)
-	p := New(l)
-	program := p.ParseProgram()
-	checkParsedErrors(t, p)
-
-	if len(program.Statements) < 3 {
-		t.Fatalf("Expected length to be 3 but got %d", len(program.Statements))
+	tests := []struct {
+		input         string
+		expectedValue interface{}
+	}{
+		{"return 5;", 5},
+		{"return 10;", 10},
+		{"return foobar;", "foobar"},
+		{"return 993322", 993322},
 	}
-	for _, st := range program.Statements {
-		rtStm, ok := st.(*ast.ReturnStatement)
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParsedErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Expected length to be 1 but got %d", len(program.Statements))
+		}
+		rtStm, ok := program.Statements[0].(*ast.ReturnStatement)
 		if !ok {
 			t.Fatal("Not a return statement")
 		}
 		if rtStm.TokenLiteral() != "return" {
 			t.Errorf("ReturnStatement.TokenLiteral not 'return', got %q", rtStm.TokenLiteral())
 		}
-
+		if !testLiteralExpression(t, rtStm.ReturnValue, tt.expectedValue) {
+			return
+		}
 	}
 }
 
@@ -151,6 +188,80 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerLiteralForms(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF", 255},
+		{"0o755", 493},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"9223372036854775807", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParsedErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		integer, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("expected stmt.Expression to be *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if integer.Value != tt.expected {
+			t.Errorf("input %s: integer.Value not %d. got=%d", tt.input, tt.expected, integer.Value)
+		}
+	}
+
+	for _, input := range []string{"9223372036854775808", "0x8000_0000_0000_0000"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		errors := p.Errors()
+		want := "integer " + input + " does not fit in 64 bits; the largest integer is 9223372036854775807"
+		if len(errors) != 1 || errors[0].Code != CodeInvalidInteger || errors[0].Message != want {
+			t.Errorf("input %s: expected %s error %q, got %v", input, CodeInvalidInteger, want, errors)
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"1e-9", 1e-9},
+		{"2.5E3", 2500},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParsedErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		float, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("expected stmt.Expression to be *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if float.Value != tt.expected {
+			t.Errorf("float.Value not %g. got=%g", tt.expected, float.Value)
+		}
+		if float.String() != tt.input {
+			t.Errorf("float.String() not %s. got=%s", tt.input, float.String())
+		}
+	}
+
+	p := New(lexer.New("1e999"))
+	p.ParseProgram()
+	errors := p.Errors()
+	if len(errors) != 1 || errors[0].Code != CodeInvalidFloat {
+		t.Fatalf("expected one %s error, got %v", CodeInvalidFloat, errors)
+	}
+}
+
 func TestParsingPrefixExpression(t *testing.T) {
 
 	prefixExpressions := []struct {
@@ -224,6 +335,11 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"5 && 5;", 5, "&&", 5},
+		{"5 || 5;", 5, "||", 5},
 	}
 
 	for _, tt := range infixTests {
@@ -336,6 +452,98 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"!(true == true)",
 			"(!(true == true))",
 		},
+		{
+			"a + add(b * c) + d",
+			"((a + add((b * c))) + d)",
+		},
+		{
+			"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))",
+			"add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))",
+		},
+		{
+			"add(a + b + c * d / f + g)",
+			"add((((a + b) + ((c * d) / f)) + g))",
+		},
+		{
+			"-add(x)",
+			"(-add(x))",
+		},
+		{
+			"fn(x) { x }(5) * 2",
+			"(fn(x) { x }(5) * 2)",
+		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"f(x)[0]",
+			"(f(x)[0])",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a == b && c != d || !e",
+			"(((a == b) && (c != d)) || (!e))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"a * b ** c * d",
+			"((a * (b ** c)) * d)",
+		},
+		{
+			"a ** -b ** c",
+			"(a ** (-(b ** c)))",
+		},
+		{
+			"a ** b[0] ** f(c)",
+			"(a ** ((b[0]) ** f(c)))",
+		},
+		{
+			"a - b - c",
+			"((a - b) - c)",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b == c",
+			"(a & (b == c))",
+		},
+		{
+			"a << b + c < d >> e",
+			"((a << (b + c)) < (d >> e))",
+		},
+		{
+			"~a & b || c | d && e",
+			"(((~a) & b) || ((c | d) && e))",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -375,6 +583,8 @@ func testLiteralExpression(t *testing.T, exp ast.Expression, expected interface{
 		return testIntegerLiteral(t, exp, v)
 	case string:
 		return testIdentifier(t, exp, v)
+	case bool:
+		return testBooleanLiteral(t, exp, v)
 	}
 	t.Errorf("type of exp not handled. got=%T", exp)
 	return false
@@ -444,3 +654,621 @@ func TestBooleanExpression(t *testing.T) {
 		t.Errorf("boo.TokenLiteral not set to 'False'. got=%s", boo.TokenLiteral())
 	}
 }
+
+func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
+	bo, ok := exp.(*ast.Boolean)
+	if !ok {
+		t.Errorf("exp not *ast.Boolean. got=%T", exp)
+		return false
+	}
+	if bo.Value != value {
+		t.Errorf("bo.Value not %t. got=%t", value, bo.Value)
+		return false
+	}
+	if bo.TokenLiteral() != fmt.Sprintf("%t", value) {
+		t.Errorf("bo.TokenLiteral not %t. got=%s", value, bo.TokenLiteral())
+		return false
+	}
+	return true
+}
+
+func TestIfExpression(t *testing.T) {
+	input := `if (x < y) { x }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+	if len(exp.Consequence.Statements) != 1 {
+		t.Errorf("consequence is not 1 statements. got=%d", len(exp.Consequence.Statements))
+	}
+	consequence, ok := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", exp.Consequence.Statements[0])
+	}
+	if !testIdentifier(t, consequence.Expression, "x") {
+		return
+	}
+	if exp.Alternative != nil {
+		t.Errorf("exp.Alternative was not nil. got=%+v", exp.Alternative)
+	}
+}
+
+func TestIfElseExpression(t *testing.T) {
+	input := `if (x < y) { x } else { y }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+	}
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+	consequence := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+	if !testIdentifier(t, consequence.Expression, "x") {
+		return
+	}
+	if exp.Alternative == nil || len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative is not 1 statements. got=%+v", exp.Alternative)
+	}
+	alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", exp.Alternative.Statements[0])
+	}
+	if !testIdentifier(t, alternative.Expression, "y") {
+		return
+	}
+}
+
+func TestElseIfExpression(t *testing.T) {
+	input := `if (a) { 1 } else if (b) { 2 } else { 3 }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+	if exp.Alternative == nil || len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative is not 1 statements. got=%+v", exp.Alternative)
+	}
+	nestedStmt, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", exp.Alternative.Statements[0])
+	}
+	nested, ok := nestedStmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("else branch is not ast.IfExpression. got=%T", nestedStmt.Expression)
+	}
+	if !testIdentifier(t, nested.Condition, "b") {
+		return
+	}
+	if nested.Alternative == nil {
+		t.Fatal("nested.Alternative is nil")
+	}
+
+	expected := "if a { 1 } else { if b { 2 } else { 3 } }"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestIfExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"if (x) { x", "1:11: unexpected end of input, expected }"},
+		{"if (x) { x } else {", "1:20: unexpected end of input, expected }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("input %q: expected parser errors", tt.input)
+		}
+		if errors[0].Error() != tt.expectedError {
+			t.Errorf("input %q: wrong error. expected=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y; }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+	}
+	if len(function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d", len(function.Parameters))
+	}
+	testLiteralExpression(t, function.Parameters[0], "x")
+	testLiteralExpression(t, function.Parameters[1], "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf("function.Body.Statements has not 1 statements. got=%d", len(function.Body.Statements))
+	}
+	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T", function.Body.Statements[0])
+	}
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+	}{
+		{input: "fn() {};", expectedParams: []string{}},
+		{input: "fn(x) {};", expectedParams: []string{"x"}},
+		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParsedErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Errorf("length parameters wrong. want %d, got=%d", len(tt.expectedParams), len(function.Parameters))
+		}
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+	}
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+	input := "add(1, 2 * 3, 4 + 5);"
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, exp.Function, "add") {
+		return
+	}
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+	testLiteralExpression(t, exp.Arguments[0], 1)
+	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
+	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+}
+
+func TestImmediatelyInvokedFunction(t *testing.T) {
+	input := "fn(x){x}(5)"
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+	if _, ok := call.Function.(*ast.FunctionLiteral); !ok {
+		t.Fatalf("call.Function is not ast.FunctionLiteral. got=%T", call.Function)
+	}
+	if len(call.Arguments) != 1 || !testLiteralExpression(t, call.Arguments[0], 5) {
+		t.Fatalf("wrong arguments. got=%v", call.Arguments)
+	}
+}
+
+func TestNodeSpans(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+};
+add(1, 2 * 3);`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	tests := []struct {
+		node      ast.Node
+		start     string
+		end       string
+		endOffset int
+	}{
+		{program, "1:1", "4:14", len(input) - 1},
+		{program.Statements[0], "1:1", "3:2", 30},
+		{program.Statements[0].(*ast.LetStatement).Value, "1:11", "3:2", 30},
+		{program.Statements[1], "4:1", "4:14", len(input) - 1},
+		{program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression).Arguments[1], "4:8", "4:13", len(input) - 2},
+	}
+
+	for i, tt := range tests {
+		span := tt.node.Span()
+		if span.Start.String() != tt.start {
+			t.Errorf("tests[%d] %q: wrong start. want=%s, got=%s", i, tt.node, tt.start, span.Start)
+		}
+		if span.End.String() != tt.end {
+			t.Errorf("tests[%d] %q: wrong end. want=%s, got=%s", i, tt.node, tt.end, span.End)
+		}
+		if span.End.Offset != tt.endOffset {
+			t.Errorf("tests[%d] %q: wrong end offset. want=%d, got=%d", i, tt.node, tt.endOffset, span.End.Offset)
+		}
+	}
+}
+
+func TestExpectPeekErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedStart   string
+	}{
+		{"let = 5;", "expected next token to be IDENT, got = instead", "1:5"},
+		{"let x 5;", "expected next token to be =, got INT instead", "1:7"},
+		{"fn(x y) { x }", "expected next token to be ), got IDENT instead", "1:6"},
+		{"fn(x, ) { x }", "expected next token to be IDENT, got ) instead", "1:7"},
+		{"if x { x }", "expected next token to be (, got IDENT instead", "1:4"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("input %q: expected parser errors", tt.input)
+			continue
+		}
+		d := errors[0]
+		if d.Severity != SeverityError || d.Code != CodeUnexpectedToken {
+			t.Errorf("input %q: wrong severity or code. got=%s %s", tt.input, d.Severity, d.Code)
+		}
+		if d.Message != tt.expectedMessage {
+			t.Errorf("input %q: wrong message. expected=%q, got=%q", tt.input, tt.expectedMessage, d.Message)
+		}
+		if d.Span.Start.String() != tt.expectedStart {
+			t.Errorf("input %q: wrong start. expected=%s, got=%s", tt.input, tt.expectedStart, d.Span.Start)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	input := `
+let x 5;
+let y = 10;
+let = 3;
+let add = fn(a, b) {
+  let c = ;
+  a + b
+};
+return y;
+`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+
+	expectedErrors := []struct {
+		code  Code
+		start string
+	}{
+		{CodeUnexpectedToken, "2:7"},
+		{CodeUnexpectedToken, "4:5"},
+		{CodeNoPrefixParseFn, "6:11"},
+	}
+	errors := p.Errors()
+	if len(errors) != len(expectedErrors) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expectedErrors), len(errors), errors)
+	}
+	for i, want := range expectedErrors {
+		if errors[i].Code != want.code || errors[i].Span.Start.String() != want.start {
+			t.Errorf("errors[%d]: expected %s at %s, got %s at %s", i, want.code, want.start, errors[i].Code, errors[i].Span.Start)
+		}
+	}
+
+	expected := "let y = 10;let add = fn(a, b) { (a + b) };return y;"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello\tworld";`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != "hello\tworld" {
+		t.Errorf("literal.Value not %q. got=%q", "hello\tworld", literal.Value)
+	}
+	if program.String() != `"hello\tworld"` {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestLexerErrorsBecomeDiagnostics(t *testing.T) {
+	input := `let a = "x" + "y;
+let b = 1;`
+	p := New(lexer.New(input))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errors), errors)
+	}
+	if errors[0].Code != CodeUnexpectedEOF || errors[0].Message != "unterminated string literal" {
+		t.Errorf("wrong diagnostic. got=%s %q", errors[0].Code, errors[0].Message)
+	}
+	if errors[0].Span.Start.String() != "1:15" || errors[0].Span.End.Offset != len(input) {
+		t.Errorf("wrong span. got=%s-%d", errors[0].Span.Start, errors[0].Span.End.Offset)
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+	if array.Span().End.Offset != len(input) {
+		t.Errorf("array span should end at %d. got=%d", len(input), array.Span().End.Offset)
+	}
+}
+
+func TestParsingEmptyArrayLiteral(t *testing.T) {
+	p := New(lexer.New("[]"))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+	if len(array.Elements) != 0 {
+		t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+	if indexExp.Span().End.Offset != len(input) {
+		t.Errorf("index span should end at %d. got=%d", len(input), indexExp.Span().End.Offset)
+	}
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	input := `{"one": 1, "two": 2, "three": 3}`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	expected := []struct {
+		key   string
+		value int64
+	}{{"one", 1}, {"two", 2}, {"three", 3}}
+	if len(hash.Pairs) != len(expected) {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+	for i, want := range expected {
+		literal, ok := hash.Pairs[i].Key.(*ast.StringLiteral)
+		if !ok {
+			t.Errorf("key is not ast.StringLiteral. got=%T", hash.Pairs[i].Key)
+			continue
+		}
+		if literal.Value != want.key {
+			t.Errorf("pair %d has wrong key. want=%q, got=%q", i, want.key, literal.Value)
+		}
+		testIntegerLiteral(t, hash.Pairs[i].Value, want.value)
+	}
+	if program.String() != input {
+		t.Errorf("program.String() wrong. want=%q, got=%q", input, program.String())
+	}
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+	p := New(lexer.New("{}"))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+	if len(hash.Pairs) != 0 {
+		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+}
+
+func TestParsingHashLiteralsWithExpressions(t *testing.T) {
+	input := `{"one": 0 + 1, true: 10 - 8, 3: 15 / 5,}`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParsedErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+	if len(hash.Pairs) != 3 {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+	testInfixExpression(t, hash.Pairs[0].Value, 0, "+", 1)
+	testBooleanLiteral(t, hash.Pairs[1].Key, true)
+	testInfixExpression(t, hash.Pairs[1].Value, 10, "-", 8)
+	testIntegerLiteral(t, hash.Pairs[2].Key, 3)
+	testInfixExpression(t, hash.Pairs[2].Value, 15, "/", 5)
+}
+
+func TestBlockInExpressionPosition(t *testing.T) {
+	tests := []string{
+		"{ let x = 1; }",
+		"let f = { x; };",
+		"{ x }",
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("input %q: expected parser errors", input)
+			continue
+		}
+		if errors[0].Hint != blockInExpressionHint {
+			t.Errorf("input %q: expected the block hint. got=%q", input, errors[0].Hint)
+		}
+	}
+}
+
+func TestNestingDepthLimit(t *testing.T) {
+	tests := []string{
+		strings.Repeat("(", 2000) + "1" + strings.Repeat(")", 2000),
+		strings.Repeat("-", 2000) + "1",
+		strings.Repeat("[", 2000) + "1" + strings.Repeat("]", 2000),
+		strings.Repeat("if (x) { ", 600) + "1" + strings.Repeat(" }", 600),
+		"1" + strings.Repeat(" + 1", 100000),
+		"f" + strings.Repeat("(1)", 2000),
+		"a" + strings.Repeat("[0]", 2000),
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("input %.10q...: expected 1 error, got %d", input, len(errors))
+			continue
+		}
+		if errors[0].Code != CodeNestingTooDeep {
+			t.Errorf("input %.10q...: wrong code %s: %s", input, errors[0].Code, errors[0].Message)
+		}
+	}
+
+	for _, input := range []string{
+		strings.Repeat("(", MaxNestingDepth-1) + "1" + strings.Repeat(")", MaxNestingDepth-1),
+		"1" + strings.Repeat(" + 1", MaxNestingDepth-1),
+		strings.Repeat("(1 + ", 250) + "1" + strings.Repeat(")", 250),
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		checkParsedErrors(t, p)
+	}
+}
+
+func TestIncompleteInput(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{"let f = fn(x) {", true},
+		{"let f = fn(x) {\n  x +", true},
+		{"add(1, 2", true},
+		{"add(1,", true},
+		{"if (x) { 1 } else {", true},
+		{"let a = [1, 2", true},
+		{`let h = {"a": 1`, true},
+		{`let s = "multi`, true},
+		{"let x =", true},
+		{"let f = fn(x) { x };", false},
+		{"let x 5;", false},
+		{"let x 5; fn(x) {", false},
+		{"add(1 2", false},
+		{"1 + @", false},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if got := p.Incomplete(); got != tt.incomplete {
+			t.Errorf("input %q: Incomplete() = %t, want %t (errors %v)", tt.input, got, tt.incomplete, p.Errors())
+		}
+	}
+
+	p := New(lexer.New("add(1, 2"))
+	p.ParseProgram()
+	if msg := p.Errors()[0].Message; msg != "unexpected end of input, expected )" {
+		t.Errorf("wrong message. got=%q", msg)
+	}
+}
@@ -2,27 +2,325 @@ package repl
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/dawkaka/go-interpreter/ast"
+	"github.com/dawkaka/go-interpreter/compiler"
+	"github.com/dawkaka/go-interpreter/evaluator"
 	"github.com/dawkaka/go-interpreter/lexer"
+	"github.com/dawkaka/go-interpreter/lineedit"
+	"github.com/dawkaka/go-interpreter/object"
+	"github.com/dawkaka/go-interpreter/parser"
+	"github.com/dawkaka/go-interpreter/report"
 	"github.com/dawkaka/go-interpreter/token"
+	"github.com/dawkaka/go-interpreter/vm"
 )
 
 const PROMPT = ">> "
 
+// CONTINUATION_PROMPT is shown while an incomplete input is being continued.
+const CONTINUATION_PROMPT = ".. "
+
+const help = `:eval        evaluate input (the default)
+:tokens      print the tokens of input
+:ast         print the parsed program
+:bytecode    print the compiled instructions, then run them
+:env         list the bindings made so far
+:reset       forget all bindings
+:load FILE   run FILE in the current mode
+:help        show this message
+`
+
+type mode int
+
+const (
+	modeEval mode = iota
+	modeTokens
+	modeAST
+	modeBytecode
+)
+
+var modes = map[string]mode{
+	":eval":     modeEval,
+	":tokens":   modeTokens,
+	":ast":      modeAST,
+	":bytecode": modeBytecode,
+}
+
+// session is the state kept between lines of input.
+type session struct {
+	out      io.Writer
+	renderer *report.Renderer
+	mode     mode
+
+	// env holds the bindings made in :eval mode. Its outer environment binds
+	// puts to out, so that the binding does not show up in :env.
+	env *object.Environment
+
+	// The state of :bytecode mode, which has bindings of its own.
+	symbolTable *compiler.SymbolTable
+	constants   []object.Object
+	globals     []object.Object
+
+	// Every input is lexed as its own file so that an error raised inside a
+	// function defined on an earlier line is shown against that line.
+	inputs  int
+	sources map[string]string
+}
+
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	s := &session{
+		out:      out,
+		renderer: report.NewRenderer(out),
+		sources:  map[string]string{},
+	}
+	s.reset()
+
+	var lines lineReader = &scannerReader{scanner: bufio.NewScanner(in), out: out}
+	if lineedit.IsTerminal(in) {
+		lines = s.newEditor(in)
+	}
+
+	// Lines are buffered while they only make up the start of a program, as
+	// in "let f = fn(x) {". A blank line runs whatever has been buffered.
+	var pending []string
 	for {
-		fmt.Println(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		prompt := PROMPT
+		if len(pending) != 0 {
+			prompt = CONTINUATION_PROMPT
+		}
+		line, err := lines.ReadLine(prompt)
+		if err == lineedit.ErrInterrupted {
+			pending = nil
+			continue
+		}
+		if err != nil {
+			if len(pending) != 0 {
+				s.submit(strings.Join(pending, "\n"))
+			}
 			return
 		}
-		line := scanner.Text()
-		l := lexer.New(line)
-		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
-			fmt.Printf("%+v\n", tok)
+
+		switch {
+		case len(pending) == 0 && strings.HasPrefix(strings.TrimSpace(line), ":"):
+			s.command(strings.Fields(line))
+		case len(pending) != 0 && strings.TrimSpace(line) == "":
+			s.submit(strings.Join(pending, "\n"))
+			pending = nil
+		default:
+			pending = append(pending, line)
+			src := strings.Join(pending, "\n")
+			if s.mode == modeTokens || !incomplete(src) {
+				s.submit(src)
+				pending = nil
+			}
+		}
+	}
+}
+
+// lineReader reads the input of the REPL one line at a time.
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// scannerReader reads lines from input that is not a terminal, such as the
+// input of tests.
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scannerReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// HistoryFile is the name of the file in the user's home directory that
+// keeps the lines entered at a terminal.
+const HistoryFile = ".monkey_history"
+
+func (s *session) newEditor(in io.Reader) *lineedit.Editor {
+	editor := lineedit.New(in, s.out)
+	editor.Complete = s.complete
+	if home, err := os.UserHomeDir(); err == nil {
+		if history, err := lineedit.OpenHistory(filepath.Join(home, HistoryFile)); err == nil {
+			editor.History = history
+		}
+	}
+	return editor
+}
+
+// complete returns the keywords, builtins and bindings that start with
+// prefix.
+func (s *session) complete(prefix string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, list := range [][]string{token.Keywords(), object.BuiltinNames(), s.env.Names()} {
+		for _, name := range list {
+			if strings.HasPrefix(name, prefix) && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
 		}
 	}
+	sort.Strings(names)
+	return names
+}
+
+// incomplete reports whether src is the start of a program rather than a
+// wrong one.
+func incomplete(src string) bool {
+	p := parser.New(lexer.New(src))
+	p.ParseProgram()
+	return p.Incomplete()
+}
+
+// submit runs a complete input typed at the prompt.
+func (s *session) submit(src string) {
+	s.inputs++
+	s.run(fmt.Sprintf("<repl#%d>", s.inputs), src)
+}
+
+func (s *session) reset() {
+	prelude := object.NewEnvironment()
+	prelude.Set("puts", object.NewPuts(s.out))
+	s.env = object.NewEnclosedEnvironment(prelude)
+
+	s.symbolTable = compiler.NewSymbolTable()
+	s.constants = []object.Object{}
+	s.globals = make([]object.Object, vm.GlobalsSize)
+}
+
+func (s *session) command(fields []string) {
+	name, args := fields[0], fields[1:]
+	if m, ok := modes[name]; ok && len(args) == 0 {
+		s.mode = m
+		return
+	}
+	switch {
+	case name == ":env" && len(args) == 0:
+		for _, name := range s.env.Names() {
+			val, _ := s.env.Get(name)
+			fmt.Fprintf(s.out, "%s = %s\n", name, val.Inspect())
+		}
+	case name == ":reset" && len(args) == 0:
+		s.reset()
+	case name == ":load" && len(args) == 1:
+		src, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintf(s.out, "error: %s\n", err)
+			return
+		}
+		s.run(args[0], string(src))
+	case name == ":help" && len(args) == 0:
+		io.WriteString(s.out, help)
+	default:
+		fmt.Fprintf(s.out, "error: unknown command %q, try :help\n", strings.Join(fields, " "))
+	}
+}
+
+// run handles src, read from filename, according to the current mode.
+func (s *session) run(filename, src string) {
+	s.sources[filename] = src
+	if s.mode == modeTokens {
+		s.printTokens(filename, src)
+		return
+	}
+
+	p := parser.New(lexer.NewFile(filename, src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, s.renderer, src, p.Errors())
+		return
+	}
+
+	switch s.mode {
+	case modeAST:
+		for _, stmt := range program.Statements {
+			fmt.Fprintln(s.out, stmt.String())
+		}
+	case modeBytecode:
+		s.runBytecode(program)
+	default:
+		s.eval(program)
+	}
+}
+
+func (s *session) printTokens(filename, src string) {
+	l := lexer.NewFile(filename, src)
+	l.Error = func(span token.Span, msg string) {
+		s.renderer.Render(s.out, src, report.Report{Severity: "error", Message: msg, Span: span})
+	}
+	for {
+		tok := l.NextToken()
+		fmt.Fprintf(s.out, "%d:%d\t%s\t%q\n", tok.Start.Line, tok.Start.Column, tok.Type, tok.Literal)
+		if tok.Type == token.EOF {
+			return
+		}
+	}
+}
+
+func (s *session) eval(program *ast.Program) {
+	// The meter stops runaway recursion with an error before it can exhaust
+	// the Go stack and take the REPL down with it.
+	s.env.SetMeter(object.NewMeter(context.Background(), object.Limits{MaxCallDepth: object.DefaultMaxCallDepth}))
+	defer s.env.SetMeter(nil)
+	evaluated := evaluator.Eval(program, s.env)
+	if errObj, ok := evaluated.(*object.Error); ok {
+		rep := report.FromRuntimeError(errObj)
+		s.renderer.Render(s.out, s.sources[rep.Span.Start.Filename], rep)
+		return
+	}
+	if evaluated != nil {
+		io.WriteString(s.out, evaluated.Inspect())
+		io.WriteString(s.out, "\n")
+	}
+}
+
+func (s *session) runBytecode(program *ast.Program) {
+	if len(program.Statements) == 0 {
+		return
+	}
+	// A failed input defines nothing: names it let-bound may never have
+	// been given a value in the globals store.
+	symbolTable := s.symbolTable.Copy()
+	comp := compiler.NewWithState(symbolTable, s.constants)
+	if err := comp.Compile(program); err != nil {
+		fmt.Fprintf(s.out, "error: %s\n", err)
+		return
+	}
+	bytecode := comp.Bytecode()
+	io.WriteString(s.out, bytecode.Instructions.String())
+
+	machine := vm.NewWithGlobalsStore(bytecode, s.globals)
+	if err := machine.Run(); err != nil {
+		fmt.Fprintf(s.out, "error: %s\n", err)
+		return
+	}
+	s.symbolTable = symbolTable
+	s.constants = bytecode.Constants
+	// Only expression statements leave a value behind to print.
+	n := len(program.Statements)
+	if _, ok := program.Statements[n-1].(*ast.ExpressionStatement); ok {
+		io.WriteString(s.out, machine.LastPoppedStackElem().Inspect())
+		io.WriteString(s.out, "\n")
+	}
+}
+
+func printParserErrors(out io.Writer, renderer *report.Renderer, source string, errors []*parser.Diagnostic) {
+	for _, d := range errors {
+		renderer.Render(out, source, report.FromDiagnostic(d))
+	}
 }
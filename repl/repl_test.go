@@ -0,0 +1,150 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dawkaka/go-interpreter/object"
+)
+
+func runREPL(t *testing.T, lines ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	Start(strings.NewReader(strings.Join(lines, "\n")+"\n"), &out)
+	return strings.ReplaceAll(out.String(), PROMPT, "")
+}
+
+func TestEvalMode(t *testing.T) {
+	out := runREPL(t,
+		"let x = 5;",
+		"x * 2",
+		`puts("hi", x)`,
+		"let add = fn(a, b) { a + b };",
+		"add(x, true)",
+	)
+	for _, want := range []string{"10\n", "hi\n5\nnull\n", "type mismatch: INTEGER + BOOLEAN", "--> <repl#4>:1:22"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestModes(t *testing.T) {
+	tests := []struct {
+		lines    []string
+		expected string
+	}{
+		{[]string{":tokens", "let x = 1;"}, "1:1\tLET\t\"let\"\n1:5\tIDENT\t\"x\"\n1:7\t=\t\"=\"\n1:9\tINT\t\"1\"\n1:10\t;\t\";\"\n1:11\tEOF\t\"\"\n"},
+		{[]string{":ast", "-1 + 2 * x"}, "((-1) + (2 * x))\n"},
+		{[]string{":bytecode", "1 + 2"}, "0000 OpConstant 0\n0003 OpConstant 1\n0006 OpAdd\n0007 OpPop\n3\n"},
+		{[]string{":bytecode", "let a = 2;", "a * a"}, "0000 OpConstant 0\n0003 OpSetGlobal 0\n0000 OpGetGlobal 0\n0003 OpGetGlobal 0\n0006 OpMul\n0007 OpPop\n4\n"},
+		{[]string{":ast", ":eval", "1 + 1"}, "2\n"},
+		{[]string{":bytecode", "let x = 1 / 0;", "x"}, "0000 OpConstant 0\n0003 OpConstant 1\n0006 OpDiv\n0007 OpSetGlobal 0\nerror: division by zero: 1 / 0\nerror: undefined variable x\n"},
+		{[]string{":bytecode", "let a = 1; b", "a"}, "error: undefined variable b\nerror: undefined variable a\n"},
+	}
+
+	for _, tt := range tests {
+		if out := runREPL(t, tt.lines...); out != tt.expected {
+			t.Errorf("%q: wrong output.\nwant=%q\ngot= %q", tt.lines, tt.expected, out)
+		}
+	}
+}
+
+func TestRunawayRecursion(t *testing.T) {
+	out := runREPL(t, "let f = fn(x) { f(x) };", "f(1)", "1")
+	if !strings.Contains(out, "call depth limit exceeded (10000)") || !strings.HasSuffix(out, "\n1\n") {
+		t.Errorf("recursion was not stopped with an error:\n%s", out)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	out := runREPL(t, "let b = 2;", "let a = [1];", ":env")
+	if !strings.HasSuffix(out, "a = [1]\nb = 2\n") {
+		t.Errorf(":env listed wrong bindings:\n%s", out)
+	}
+
+	out = runREPL(t, "let a = 1;", ":reset", "a", ":env")
+	if !strings.Contains(out, "identifier not found: a") {
+		t.Errorf(":reset kept bindings:\n%s", out)
+	}
+
+	script := filepath.Join(t.TempDir(), "lib.mk")
+	if err := os.WriteFile(script, []byte("let double = fn(x) {\n  x * 2\n};\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out = runREPL(t, ":load "+script, "double(21)", `double("a")`)
+	if !strings.Contains(out, "42\n") {
+		t.Errorf(":load did not define double:\n%s", out)
+	}
+	if !strings.Contains(out, "--> "+script+":2:3") {
+		t.Errorf("error not located in the loaded file:\n%s", out)
+	}
+
+	out = runREPL(t, ":load", ":bogus", ":load "+filepath.Join(t.TempDir(), "missing.mk"))
+	for _, want := range []string{`unknown command ":load"`, `unknown command ":bogus"`, "no such file"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMultiLineInput(t *testing.T) {
+	var out bytes.Buffer
+	input := strings.Join([]string{
+		"let add = fn(a, b) {",
+		"  a + b",
+		"};",
+		"add(1,",
+		"2)",
+		`let s = "two`,
+		`lines";`,
+		"s",
+		"let broken = fn(x) {",
+		"",
+		"add(2, 3",
+	}, "\n")
+	Start(strings.NewReader(input), &out)
+
+	got := out.String()
+	for _, want := range []string{
+		">> .. .. >> .. 3\n",
+		">> .. >> two\nlines\n",
+		"unexpected end of input, expected }",
+		"unexpected end of input, expected )",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "identifier not found") {
+		t.Errorf("incomplete input was evaluated early:\n%s", got)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	s := &session{out: io.Discard}
+	s.reset()
+	s.env.Set("length", object.NULL)
+	s.env.Set("result", object.NULL)
+
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"le", []string{"len", "length", "let"}},
+		{"re", []string{"rest", "result", "return"}},
+		{"pu", []string{"push", "puts"}},
+		{"zz", nil},
+	}
+
+	for _, tt := range tests {
+		if got := s.complete(tt.prefix); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("complete(%q) = %q, want %q", tt.prefix, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package report renders parser diagnostics and runtime errors against the
+// source they refer to, with the offending span underlined:
+//
+//	error[P0001]: expected next token to be =, got INT instead
+//	 --> main.mk:2:7
+//	  |
+//	2 | let x 5;
+//	  |       ^
+//	  = hint: let bindings are written as `let name = value;`
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dawkaka/go-interpreter/object"
+	"github.com/dawkaka/go-interpreter/parser"
+	"github.com/dawkaka/go-interpreter/token"
+)
+
+// Report is a single message about a span of source.
+type Report struct {
+	Severity string
+	Code     string
+	Message  string
+	Hint     string
+	Span     token.Span
+}
+
+func FromDiagnostic(d *parser.Diagnostic) Report {
+	return Report{
+		Severity: d.Severity.String(),
+		Code:     string(d.Code),
+		Message:  d.Message,
+		Hint:     d.Hint,
+		Span:     d.Span,
+	}
+}
+
+func FromRuntimeError(e *object.Error) Report {
+	return Report{
+		Severity: "runtime error",
+		Message:  e.Message,
+		Span:     e.Span,
+	}
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiBlue   = "\x1b[1;34m"
+	ansiCyan   = "\x1b[1;36m"
+)
+
+// Renderer writes reports in a rustc-like layout. With Color set the output
+// contains ANSI escape sequences.
+type Renderer struct {
+	Color bool
+}
+
+// NewRenderer returns a renderer that uses colour only when w is a terminal
+// and the NO_COLOR environment variable is not set.
+func NewRenderer(w io.Writer) *Renderer {
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return &Renderer{Color: !noColor && IsTerminal(w)}
+}
+
+// IsTerminal reports whether w is a character device such as a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (r *Renderer) paint(style, s string) string {
+	if !r.Color {
+		return s
+	}
+	return style + s + ansiReset
+}
+
+// Render writes rep to w. source is the full text the report's span points
+// into; if the span lies outside of it only the header is written.
+func (r *Renderer) Render(w io.Writer, source string, rep Report) error {
+	var out strings.Builder
+
+	style := ansiRed
+	if rep.Severity == parser.SeverityWarning.String() {
+		style = ansiYellow
+	}
+	header := rep.Severity
+	if rep.Code != "" {
+		header += "[" + rep.Code + "]"
+	}
+	out.WriteString(r.paint(style, header))
+	out.WriteString(r.paint(ansiBold, ": "+rep.Message))
+	out.WriteString("\n")
+
+	start := rep.Span.Start
+	line, ok := sourceLine(source, start)
+	if !ok {
+		if start.IsValid() || start.Filename != "" {
+			fmt.Fprintf(&out, " %s %s\n", r.paint(ansiBlue, "-->"), start)
+		}
+		r.writeHint(&out, "", rep.Hint)
+		_, err := io.WriteString(w, out.String())
+		return err
+	}
+
+	lineNo := strconv.Itoa(start.Line)
+	gutter := strings.Repeat(" ", len(lineNo))
+	fmt.Fprintf(&out, "%s%s %s\n", gutter, r.paint(ansiBlue, "-->"), start)
+	fmt.Fprintf(&out, "%s %s\n", gutter, r.paint(ansiBlue, "|"))
+	fmt.Fprintf(&out, "%s %s %s\n", r.paint(ansiBlue, lineNo), r.paint(ansiBlue, "|"), line)
+	fmt.Fprintf(&out, "%s %s %s%s\n", gutter, r.paint(ansiBlue, "|"),
+		padding(line, start.Column-1), r.paint(style, carets(line, rep.Span)))
+	r.writeHint(&out, gutter, rep.Hint)
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func (r *Renderer) writeHint(out *strings.Builder, gutter, hint string) {
+	if hint == "" {
+		return
+	}
+	fmt.Fprintf(out, "%s %s %s\n", gutter, r.paint(ansiBlue, "="), r.paint(ansiCyan, "hint: ")+hint)
+}
+
+// sourceLine returns the line of source that pos is on, without its line
+// terminator.
+func sourceLine(source string, pos token.Position) (string, bool) {
+	if !pos.IsValid() || pos.Offset > len(source) {
+		return "", false
+	}
+	begin := strings.LastIndexByte(source[:pos.Offset], '\n') + 1
+	end := strings.IndexByte(source[pos.Offset:], '\n')
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += pos.Offset
+	}
+	return strings.TrimSuffix(source[begin:end], "\r"), true
+}
+
+// padding returns whitespace as wide as the first n bytes of line, keeping
+// tabs so that the carets line up with the source above them.
+func padding(line string, n int) string {
+	if n > len(line) {
+		n = len(line)
+	}
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if line[i] == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// carets underlines span on line. Spans running past the end of the line are
+// cut off there, and empty spans get a single caret.
+func carets(line string, span token.Span) string {
+	width := span.End.Offset - span.Start.Offset
+	if span.End.Line != span.Start.Line {
+		width = len(line) - (span.Start.Column - 1)
+	}
+	if width < 1 {
+		width = 1
+	}
+	return strings.Repeat("^", width)
+}
@@ -0,0 +1,105 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dawkaka/go-interpreter/evaluator"
+	"github.com/dawkaka/go-interpreter/lexer"
+	"github.com/dawkaka/go-interpreter/object"
+	"github.com/dawkaka/go-interpreter/parser"
+)
+
+func TestRenderParserDiagnostic(t *testing.T) {
+	source := "let a = 1;\nlet x 5;\n"
+	p := parser.New(lexer.NewFile("main.mk", source))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 parser error, got %d", len(p.Errors()))
+	}
+
+	var out bytes.Buffer
+	r := &Renderer{}
+	if err := r.Render(&out, source, FromDiagnostic(p.Errors()[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "error[P0001]: expected next token to be =, got INT instead\n" +
+		" --> main.mk:2:7\n" +
+		"  |\n" +
+		"2 | let x 5;\n" +
+		"  |       ^\n" +
+		"  = hint: let bindings are written as `let name = value;`\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestRenderRuntimeError(t *testing.T) {
+	source := "let f = fn(a) {\n\ta + true\n};\nf(1);"
+	p := parser.New(lexer.NewFile("main.mk", source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parser errors: %v", p.Errors())
+	}
+	errObj, ok := evaluator.Eval(program, object.NewEnvironment()).(*object.Error)
+	if !ok {
+		t.Fatal("expected a runtime error")
+	}
+
+	var out bytes.Buffer
+	r := &Renderer{}
+	r.Render(&out, source, FromRuntimeError(errObj))
+
+	expected := "runtime error: type mismatch: INTEGER + BOOLEAN\n" +
+		" --> main.mk:2:2\n" +
+		"  |\n" +
+		"2 | \ta + true\n" +
+		"  | \t^^^^^^^^\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant:\n%q\ngot:\n%q", expected, out.String())
+	}
+}
+
+func TestRenderMultiLineAndEmptySpans(t *testing.T) {
+	source := "if (x) {\n  1\n"
+	p := parser.New(lexer.New(source))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Fatal("expected parser errors")
+	}
+
+	var out bytes.Buffer
+	r := &Renderer{}
+	r.Render(&out, source, FromDiagnostic(p.Errors()[0]))
+	if !strings.Contains(out.String(), "3 | \n  | ^\n") {
+		t.Errorf("expected a single caret at end of input. got:\n%s", out.String())
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	source := "let x 5;"
+	p := parser.New(lexer.New(source))
+	p.ParseProgram()
+
+	var plain, colored bytes.Buffer
+	(&Renderer{}).Render(&plain, source, FromDiagnostic(p.Errors()[0]))
+	(&Renderer{Color: true}).Render(&colored, source, FromDiagnostic(p.Errors()[0]))
+
+	if strings.Contains(plain.String(), "\x1b[") {
+		t.Errorf("no-colour output contains escape sequences: %q", plain.String())
+	}
+	if !strings.Contains(colored.String(), ansiRed+"error[P0001]"+ansiReset) {
+		t.Errorf("colour output is missing the red header: %q", colored.String())
+	}
+}
+
+func TestRenderWithoutSource(t *testing.T) {
+	var out bytes.Buffer
+	r := &Renderer{}
+	r.Render(&out, "", Report{Severity: "runtime error", Message: "boom"})
+	if out.String() != "runtime error: boom\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
@@ -0,0 +1,78 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dawkaka/go-interpreter/compiler"
+	"github.com/dawkaka/go-interpreter/evaluator"
+	"github.com/dawkaka/go-interpreter/object"
+)
+
+// benchmarkPrograms are run both through the compiler/VM and through the
+// tree-walking evaluator so the two paths can be compared with benchstat.
+var benchmarkPrograms = map[string]string{
+	"arithmetic": strings.Repeat("(5 + 10 * 2 + 15 / 3) * 2 + -10; ", 200),
+	"globals": `
+let a = 1; let b = 2; let c = a + b; let d = c * c - a;
+` + strings.Repeat("let a = a + b * c - d / 2; let b = a - b; ", 200),
+	"conditionals": strings.Repeat(`
+if (1 < 2) { if (3 > 4) { 1 } else { 2 } } else { 3 };
+if (!(1 == 2) != false) { 10 * 10 };
+`, 200),
+}
+
+func BenchmarkVM(b *testing.B) {
+	for name, input := range benchmarkPrograms {
+		b.Run(name, func(b *testing.B) {
+			program := parse(input)
+			comp := compiler.New()
+			if err := comp.Compile(program); err != nil {
+				b.Fatalf("compiler error: %s", err)
+			}
+			bytecode := comp.Bytecode()
+			globals := make([]object.Object, GlobalsSize)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				vm := NewWithGlobalsStore(bytecode, globals)
+				if err := vm.Run(); err != nil {
+					b.Fatalf("vm error: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCompileAndRun(b *testing.B) {
+	for name, input := range benchmarkPrograms {
+		b.Run(name, func(b *testing.B) {
+			program := parse(input)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				comp := compiler.New()
+				if err := comp.Compile(program); err != nil {
+					b.Fatalf("compiler error: %s", err)
+				}
+				vm := New(comp.Bytecode())
+				if err := vm.Run(); err != nil {
+					b.Fatalf("vm error: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEval(b *testing.B) {
+	for name, input := range benchmarkPrograms {
+		b.Run(name, func(b *testing.B) {
+			program := parse(input)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				evaluator.Eval(program, object.NewEnvironment())
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/dawkaka/go-interpreter/ast"
+	"github.com/dawkaka/go-interpreter/compiler"
+	"github.com/dawkaka/go-interpreter/lexer"
+	"github.com/dawkaka/go-interpreter/object"
+	"github.com/dawkaka/go-interpreter/parser"
+)
+
+type vmTestCase struct {
+	input    string
+	expected interface{}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1", 1},
+		{"2", 2},
+		{"1 + 2", 3},
+		{"1 - 2", -1},
+		{"1 * 2", 2},
+		{"4 / 2", 2},
+		{"50 / 2 * 2 + 10 - 5", 55},
+		{"5 * (2 + 10)", 60},
+		{"-5", -5},
+		{"-50 + 100 + -50", 0},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 | 2 ^ 7 & 4", 7},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"2.5", 2.5},
+		{"-2.5", -2.5},
+		{"1 + 0.5", 1.5},
+		{"7 / 2.0", 3.5},
+		{"7.5 % 2", 1.5},
+		{"2.0 ** 3", 8.0},
+		{"1.0 / 0", math.Inf(1)},
+		{"1.5 < 2", true},
+		{"2 <= 1.5", false},
+		{"1 == 1.0", true},
+		{"1.5 != 1.5", false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true", true},
+		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 == 1", true},
+		{"1 != 2", true},
+		{"true == true", true},
+		{"true != false", true},
+		{"(1 < 2) == true", true},
+		{"!true", false},
+		{"!5", false},
+		{"!!5", true},
+		{"!(if (false) { 5; })", true},
+		{"1 <= 2", true},
+		{"2 <= 1", false},
+		{"2 >= 2", true},
+		{"1 >= 2", false},
+		{"true && false", false},
+		{"true && 1", true},
+		{"false || 1", true},
+		{"false || false", false},
+		{"1 > 2 || 2 < 3 && 3 < 4", true},
+		{"false && 1 / 0 == 0", false},
+		{"true || 1 / 0 == 0", true},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"if (true) { 10 }", 10},
+		{"if (true) { 10 } else { 20 }", 10},
+		{"if (false) { 10 } else { 20 } ", 20},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 > 2) { 10 }", Null},
+		{"if (false) { 10 }", Null},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 30 } else { 20 }", 30},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one", 1},
+		{"let one = 1; let two = 2; one + two", 3},
+		{"let one = 1; let two = one + one; one + two", 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStringExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`"monkey"`, "monkey"},
+		{`"mon" + "key"`, "monkey"},
+		{`"mon" + "key" + "banana"`, "monkeybanana"},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`let s = "a"; s + s == "aa"`, true},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"[]", []int{}},
+		{"[1, 2, 3]", []int{1, 2, 3}},
+		{"[1 + 2, 3 * 4, 5 + 6]", []int{3, 12, 11}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][0 + 2]", 3},
+		{"[[1, 1, 1]][0][0]", 1},
+		{"[][0]", Null},
+		{"[1, 2, 3][99]", Null},
+		{"[1][-1]", Null},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestHashLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"{}", map[object.HashKey]int64{}},
+		{
+			"{1: 2, 2: 3}",
+			map[object.HashKey]int64{
+				(&object.Integer{Value: 1}).HashKey(): 2,
+				(&object.Integer{Value: 2}).HashKey(): 3,
+			},
+		},
+		{
+			`{"a" + "b": 2 * 2, true: 6 + 4}`,
+			map[object.HashKey]int64{
+				(&object.String{Value: "ab"}).HashKey(): 4,
+				True.HashKey():                          10,
+			},
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"{1: 1, 2: 2}[1]", 1},
+		{"{1: 1, 2: 2}[2]", 2},
+		{"{1: 1}[0]", Null},
+		{"{}[0]", Null},
+		{`{"one": 1}["o" + "ne"]`, 1},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / 0", "division by zero: 1 / 0"},
+		{"1 + true", "unsupported types for binary operation: INTEGER BOOLEAN"},
+		{"-true", "unsupported type for negation: BOOLEAN"},
+		{"~true", "unsupported type for bitwise not: BOOLEAN"},
+		{"1 << 64", "shift amount out of range: 1 << 64"},
+		{"2 ** -1", "negative exponent: 2 ** -1"},
+		{"1.5 & 1", "unsupported types for binary operation: FLOAT INTEGER"},
+		{"1 >> -1", "shift amount out of range: 1 >> -1"},
+		{"1[0]", "index operator not supported: INTEGER"},
+		{"{[1]: 2}", "unusable as hash key: ARRAY"},
+		{"{1: 2}[[1]]", "unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.Bytecode())
+		err := vm.Run()
+		if err == nil {
+			t.Errorf("input %q: expected VM error", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("input %q: wrong VM error. want=%q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func runVmTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		stackElem := vm.LastPoppedStackElem()
+		testExpectedObject(t, tt.expected, stackElem)
+	}
+}
+
+func parse(input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	return p.ParseProgram()
+}
+
+func testExpectedObject(t *testing.T, expected interface{}, actual object.Object) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		if err := testIntegerObject(int64(expected), actual); err != nil {
+			t.Errorf("testIntegerObject failed: %s", err)
+		}
+	case float64:
+		result, ok := actual.(*object.Float)
+		if !ok {
+			t.Errorf("object is not Float. got=%T (%+v)", actual, actual)
+		} else if result.Value != expected {
+			t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		}
+	case bool:
+		if err := testBooleanObject(expected, actual); err != nil {
+			t.Errorf("testBooleanObject failed: %s", err)
+		}
+	case string:
+		if err := testStringObject(expected, actual); err != nil {
+			t.Errorf("testStringObject failed: %s", err)
+		}
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Errorf("object not Array: %T (%+v)", actual, actual)
+			return
+		}
+		if len(array.Elements) != len(expected) {
+			t.Errorf("wrong num of elements. want=%d, got=%d", len(expected), len(array.Elements))
+			return
+		}
+		for i, expectedElem := range expected {
+			if err := testIntegerObject(int64(expectedElem), array.Elements[i]); err != nil {
+				t.Errorf("testIntegerObject failed: %s", err)
+			}
+		}
+	case map[object.HashKey]int64:
+		hash, ok := actual.(*object.Hash)
+		if !ok {
+			t.Errorf("object is not Hash. got=%T (%+v)", actual, actual)
+			return
+		}
+		if len(hash.Pairs) != len(expected) {
+			t.Errorf("hash has wrong number of Pairs. want=%d, got=%d", len(expected), len(hash.Pairs))
+			return
+		}
+		for expectedKey, expectedValue := range expected {
+			pair, ok := hash.Pairs[expectedKey]
+			if !ok {
+				t.Errorf("no pair for given key in Pairs")
+				continue
+			}
+			if err := testIntegerObject(expectedValue, pair.Value); err != nil {
+				t.Errorf("testIntegerObject failed: %s", err)
+			}
+		}
+	case *object.Null:
+		if actual != Null {
+			t.Errorf("object is not Null: %T (%+v)", actual, actual)
+		}
+	}
+}
+
+func testIntegerObject(expected int64, actual object.Object) error {
+	result, ok := actual.(*object.Integer)
+	if !ok {
+		return fmt.Errorf("object is not Integer. got=%T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+	}
+	return nil
+}
+
+func testBooleanObject(expected bool, actual object.Object) error {
+	result, ok := actual.(*object.Boolean)
+	if !ok {
+		return fmt.Errorf("object is not Boolean. got=%T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%t, want=%t", result.Value, expected)
+	}
+	return nil
+}
+
+func testStringObject(expected string, actual object.Object) error {
+	result, ok := actual.(*object.String)
+	if !ok {
+		return fmt.Errorf("object is not String. got=%T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+	}
+	return nil
+}
+
+func TestStepLimit(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse("1 + 2 + 3 + 4")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	vm.SetMeter(object.NewMeter(context.Background(), object.Limits{MaxSteps: 3}))
+	err := vm.Run()
+	limitErr, ok := err.(*object.LimitExceeded)
+	if !ok {
+		t.Fatalf("expected *object.LimitExceeded, got %T (%v)", err, err)
+	}
+	if limitErr.Kind != object.LimitSteps {
+		t.Errorf("wrong limit kind %s", limitErr.Kind)
+	}
+
+	vm = New(comp.Bytecode())
+	vm.SetMeter(object.NewMeter(context.Background(), object.Limits{MaxSteps: 100}))
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	testExpectedObject(t, 10, vm.LastPoppedStackElem())
+}
+
+func TestMemoryLimit(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse(`let s = "aaaaaaaaaa"; let s = s + s; let s = s + s; [s, s, s]`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	vm.SetMeter(object.NewMeter(context.Background(), object.Limits{MaxMemory: 80}))
+	err := vm.Run()
+	limitErr, ok := err.(*object.LimitExceeded)
+	if !ok || limitErr.Kind != object.LimitMemory {
+		t.Fatalf("expected memory LimitExceeded, got %T (%v)", err, err)
+	}
+}